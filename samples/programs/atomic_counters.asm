@@ -0,0 +1,53 @@
+;
+; Example of atomic operations: lock-based and lock-free counters
+;
+
+LLI     R9, 0                                 ; zero constant
+LLI     R2, 1                                 ; one constant
+LLI     R10, 0                                ; lock address (0x00)
+LLI     R11, 4                                ; lock-based counter address (0x04)
+LLI     R12, 8                                ; fetch-and-add counter address (0x08)
+LLI     R13, 12                               ; compare-and-swap counter address (0x0C)
+LLI     R20, 10                               ; iterations
+LLI     R1, 0                                 ; i loop variable
+
+; for (i = 0; i < n; i+=1) {
+    LOOP:
+
+    BEQ     R1, R20, END_LOOP                 ; break if i == n
+    ADDI    R1, R1, 1                         ; i += 1
+
+    ; Lock-based increment (LL/SC spinlock)
+    ACQUIRE:
+    LL      R3, R10, 0                        ; R3 = lock
+    BNE     R3, R9, ACQUIRE                   ; spin while lock is taken
+    LLI     R3, 1
+    SC      R3, R10, 0                        ; try to take the lock
+    BEQ     R3, R9, ACQUIRE                   ; retry if the reservation was lost
+    LW      R4, R11, 0                        ; critical section
+    ADDI    R4, R4, 1
+    SW      R11, R4, 0
+    FENCE                                     ; critical section visible before release
+    SW      R10, R9, 0                        ; release lock
+
+    ; Lock-free increment (fetch-and-add)
+    AMOADD  R5, R12, R2                       ; R5 = MEM(R12), MEM(R12) += 1
+
+    ; Lock-free increment (compare-and-swap)
+    CAS_RETRY:
+    LW      R6, R13, 0                        ; expected value
+    ADDI    R8, R6, 0                         ; keep a copy of the expected value
+    ADDI    R7, R6, 1                         ; new value
+    CAS     R6, R13, R7                       ; if MEM(R13) == R6 then MEM(R13) = R7
+    BNE     R6, R8, CAS_RETRY                 ; retry if another writer got in between
+
+    J       LOOP
+; }
+
+END_LOOP:
+
+; -------------------- Output --------------------------
+;
+;    MEM(0x04) = MEM(0x08) = MEM(0x0C) = 10
+;
+; ------------------------------------------------------
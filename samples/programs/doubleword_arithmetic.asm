@@ -0,0 +1,40 @@
+;
+; Example of 64-bit arithmetic (requires "architecture_size": 64)
+;
+
+; Macros to pre-fill memory
+@0x0040: 00000001 FFFFFFFF 00000002 00000000
+
+LLI     R9, 0                                 ; output address (0x00)
+LLI     R10, 64                               ; array address (0x40)
+
+; Build R1 = 0x0000000112345678 with 64-bit shifts
+LUI     R1, 4660                              ; R1 = 0x12340000
+ADDI    R1, R1, 22136                         ; R1 = 0x12345678
+LLI     R2, 1                                 ; R2 = 1
+LLI     R3, 32                                ; R3 = 32
+SHL     R2, R2, R3                            ; R2 = 0x100000000
+ADD     R1, R1, R2                            ; R1 = 0x112345678
+
+; Doubleword loads
+LD      R4, R10, 0                            ; R4 = 0xFFFFFFFF00000001
+LD      R5, R10, 8                            ; R5 = 0x2
+ADD     R6, R4, R5                            ; R6 = 0xFFFFFFFF00000003
+ADDW    R7, R4, R5                            ; R7 = 0x3 (word result, sign-extended)
+SUBW    R8, R5, R3                            ; R8 = 0xFFFFFFFFFFFFFFE2 (-30)
+LW      R11, R10, 4                           ; R11 = 0xFFFFFFFFFFFFFFFF (sign-extended word)
+
+; Doubleword stores
+SD      R9, R1, 0                             ; MEM(0x00) = R1
+SD      R9, R6, 8                             ; MEM(0x08) = R6
+SD      R9, R7, 16                            ; MEM(0x10) = R7
+SD      R9, R8, 24                            ; MEM(0x18) = R8
+SD      R9, R11, 32                           ; MEM(0x20) = R11
+
+; -------------------- Output --------------------------
+;
+;    MEM(0x00) = 0x0000000112345678, MEM(0x08) = 0xFFFFFFFF00000003
+;    MEM(0x10) = 0x0000000000000003, MEM(0x18) = 0xFFFFFFFFFFFFFFE2
+;    MEM(0x20) = 0xFFFFFFFFFFFFFFFF
+;
+; ------------------------------------------------------
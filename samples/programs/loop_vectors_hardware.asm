@@ -0,0 +1,47 @@
+;
+; Example of filling two 40x1 arrays (A & B)
+; Filling C 40x1 array with the output from A + B
+; Sum all C elements of the array in to R15
+;    (zero-overhead hardware loops flavor)
+;
+
+; Number of words to process
+LLI     R20, 40                    ; R20 = 40 (iterations)
+
+; Initializacion memory
+LLI     R1, 0                      ; R1 = 0 (value)
+LLI     R10, 64                    ; A's memory index
+LLI     R11, 320                   ; B's memory index
+LLI     R12, 576                   ; C's memory index
+
+LOOP    R20, MEMORY_LOOP_END       ; Repeat body R20 times
+ADDI    R1, R1, 1                  ; R1 += 1
+
+SW      R10, R1, 0                 ; A[R10] = R1
+SLI     R11, 10                    ; B[R11] = 10
+LW      R13, R11, 0                ; R13 = MEM[B[I]]
+ADD     R14, R1, R13               ; R14 = A[I] + B[I]
+SW      R12, R14, 0                ; C[R12] = R14 = R1 + B[R11]
+
+; Increment array indexes
+ADDI    R10, R10, 4                ; R10 += 1 (A's index)
+ADDI    R11, R11, 4                ; R11 += 1 (B's index)
+ADDI    R12, R12, 4                ; R11 += 1 (C's index)
+MEMORY_LOOP_END:
+
+; Sum all C's elements
+LLI     R12, 576                   ; C's memory index
+LLI     R15, 0                     ; Total of C's elements
+
+LOOP    R20, PROCESS_LOOP_END      ; Repeat body R20 times
+LW      R16, R12, 0                ; R16 = C[I]
+ADD     R15, R15, R16              ; R15 += C[I]
+ADDI    R12, R12, 4                ; R12 += 1 (C's index)
+PROCESS_LOOP_END:
+
+; ---------- Expected Values -----------
+;
+;   R15 = 11 + 12 + ... + 49 + 50
+;   R15 = 0x4C4 = 1220
+;
+; --------------------------------------
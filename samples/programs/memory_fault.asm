@@ -0,0 +1,13 @@
+;
+; Example of a memory fault: a store out of the data memory stops the program when it commits
+;
+
+LLI     R2, 7                                 ; value to store
+LLI     R1, 64                                ; valid address (0x40)
+LLI     R3, 2000                              ; address out of the data memory
+
+SW      R1, R2, 0                             ; MEM(0x40) = 7
+ADDI    R4, R2, 0                             ; R4 = 7
+SW      R3, R2, 0                             ; MEM(2000) = 7, faults when committed
+ADDI    R2, R2, 1                             ; never committed
+SW      R1, R2, 4                             ; never committed
@@ -0,0 +1,37 @@
+;
+; Example of memory-mapped devices: console (0x400), timer (0x404) and random-number generator (0x408)
+;
+
+; Message "Hello!\n" at 0x40, one character per word
+@0x40: 48 65 6C 6C 6F 21 0A 00
+
+LLI     R10, 1024                             ; console address (0x400)
+LLI     R11, 1028                             ; timer address (0x404)
+LLI     R12, 1032                             ; random-number generator address (0x408)
+LLI     R9, 0                                 ; zero constant
+SUBI    R8, R9, 1                             ; end of input (-1)
+LLI     R1, 64                                ; message address (0x40)
+
+; Print the message, one character per word until a zero
+PRINT:
+    LW      R2, R1, 0                         ; R2 = MEM(R1)
+    BEQ     R2, R9, ECHO                      ; stop at the end of the message
+    SW      R10, R2, 0                        ; print character
+    ADDI    R1, R1, 4
+    J       PRINT
+
+; Echo the console input until it is exhausted
+ECHO:
+    LW      R2, R10, 0                        ; read character
+    BEQ     R2, R8, SAMPLE                    ; stop at the end of the input
+    SW      R10, R2, 0                        ; print character
+    J       ECHO
+
+; Sample the timer and the random-number generator
+SAMPLE:
+LW      R3, R11, 0                            ; R3 = cycles
+LW      R4, R12, 0                            ; R4 = random number
+LW      R5, R12, 0                            ; R5 = next random number
+SW      R9, R3, 0                             ; MEM(0x00) = R3
+SW      R9, R4, 4                             ; MEM(0x04) = R4
+SW      R9, R5, 8                             ; MEM(0x08) = R5
@@ -0,0 +1,23 @@
+;
+; Example of a page table update: 64 bytes pages, single-level page table at 0x3C0
+;
+;  - Pages 0-3 (0x000-0x0FF): code, identity mapped, read & execute
+;  - Page 4 (0x100-0x13F): mapped to 0x200, then remapped to 0x240, read & write
+;  - Page 15 (0x3C0-0x3FF): page table, identity mapped, read & write
+;
+
+; Page table entries: physical page address | flags (valid: 0x1, read: 0x2, write: 0x4, execute: 0x8)
+@0x03C0: 0B 4B 8B CB 0207
+@0x03FC: 03C7
+
+@0x0200: 01
+@0x0240: 02
+
+LLI     R1, 256                               ; virtual address (0x100)
+LW      R2, R1, 0                             ; R2 = 1 (physical 0x200)
+LLI     R3, 976                               ; page table entry of page 4 (0x3D0)
+LLI     R4, 583                               ; 0x247: physical 0x240, valid, read & write
+SW      R3, R4, 0                             ; remap page 4, younger operations are flushed
+LW      R5, R1, 0                             ; R5 = 2 (physical 0x240)
+ADD     R6, R2, R5
+SW      R1, R6, 4                             ; MEM(0x104) = 3, physical 0x244
@@ -0,0 +1,50 @@
+;
+; Example of in-program measurement with performance counters
+;
+
+; Macros to pre-fill memory
+@0x0040: 01 02 03 04 05 06 07 08 09 0A 0B 0C 0D 0E 0F 10 11 12 13 14
+
+LLI     R9, 0                                 ; output address (0x00)
+LLI     R10, 64                               ; array address (0x40)
+LLI     R12, 20                               ; array length
+LLI     R1, 0                                 ; output
+LLI     R14, 0                                ; i loop variable
+
+; Start measurement
+RDCYCLE     R20                               ; R20 = cycles
+RDINSTRET   R21                               ; R21 = instructions retired
+RDPMC       R22, 2                            ; R22 = mispredicted branches
+
+; for (i = 0; i < n; i+=1) {
+    FOR:
+
+    BEQ     R14, R12, END_FOR                 ; break if i == n
+    ADDI    R14, R14, 1                       ; i += 1
+    LW      R15, R10, 0                       ; R15 = A[i]
+    ADD     R1, R1, R15                       ; R1 += A[i]
+    ADDI    R10, R10, 4                       ; A += 4
+    J       FOR
+; }
+
+END_FOR:
+
+; Stop measurement
+RDCYCLE     R23
+RDINSTRET   R24
+RDPMC       R25, 2
+SUB     R23, R23, R20                         ; cycles spent in the loop
+SUB     R24, R24, R21                         ; instructions retired in the loop
+SUB     R25, R25, R22                         ; mispredictions in the loop
+
+SW      R9, R1, 0                             ; MEM(0x00) = sum
+SW      R9, R23, 4                            ; MEM(0x04) = cycles
+SW      R9, R24, 8                            ; MEM(0x08) = instructions
+SW      R9, R25, 12                           ; MEM(0x0C) = mispredictions
+
+; -------------------- Output --------------------------
+;
+;    MEM(0x00) = sum of A, MEM(0x04) = loop cycles
+;    MEM(0x08) = loop instructions, MEM(0x0C) = loop mispredictions
+;
+; ------------------------------------------------------
@@ -0,0 +1,39 @@
+;
+; Example of self-modifying code on a unified memory (code loaded at 0x400)
+;
+
+LLI     R1, 4                                 ; iterations
+LLI     R2, 0                                 ; sum
+LLI     R3, 0                                 ; i loop variable
+LLI     R9, 1024                              ; code base (0x400)
+
+; for (i = 0; i < n; i+=1) {
+    LOOP:
+
+    BEQ     R3, R1, END_LOOP                  ; break if i == n
+    ADDI    R3, R3, 1                         ; i += 1
+
+    PATCH:
+    ADDI    R2, R2, 1                         ; sum += 1, patched into sum += 10 on the first iteration
+
+    LW      R4, R9, 52                        ; R4 = TEMPLATE instruction (0x434)
+    SW      R9, R4, 24                        ; PATCH instruction (0x418) = R4, younger instructions are flushed
+
+    J       LOOP
+; }
+END_LOOP:
+
+; Store result in MEM(0x00)
+LLI     R14, 0
+SW      R14, R2, 0
+
+HALT                                          ; the program ends
+
+TEMPLATE:
+ADDI    R2, R2, 10
+
+; -------------------- Output --------------------------
+;
+;    MEM(0x00) = 1 + 10 + 10 + 10 = 31 (0x1F)
+;
+; ------------------------------------------------------
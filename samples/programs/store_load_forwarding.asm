@@ -0,0 +1,44 @@
+;
+; Example of memory disambiguation: store-to-load forwarding (full & partial) and ordering violations
+;
+
+@0x0044: 77
+
+LLI     R1, 64                                ; buffer address (0x40)
+LLI     R2, 64                                ; alias of the buffer address (another base register)
+LLI     R9, 72                                ; result address (0x48)
+LUI     R3, 5
+ADDI    R3, R3, 291                           ; value to store (0x00050123)
+LLI     R20, 8                                ; iterations
+LLI     R10, 0                                ; i loop variable
+LLI     R11, 0                                ; sum of the loaded values
+
+; for (i = 0; i < n; i+=1) {
+    LOOP:
+
+    BEQ     R10, R20, END_LOOP                ; break if i == n
+    ADDI    R10, R10, 1                       ; i += 1
+
+    ; Full forward through a different base register
+    SW      R1, R3, 0                         ; MEM(0x40) = R3
+    LW      R4, R2, 0                         ; R4 = MEM(0x40), forwarded from the store
+
+    ; Partial forward, the load overlaps the store and the data behind it
+    LW      R5, R2, 2                         ; R5 = MEM(0x42) = 0x00770005
+
+    ; Store address known late, the younger load goes first and it is replayed
+    LLI     R7, 9
+    SHLI    R7, R7, 3
+    ADDI    R7, R7, 0
+    ADDI    R7, R7, 0                         ; R7 = 0x48 after a chain of operations
+    SW      R7, R10, 0                        ; MEM(0x48) = i
+    LW      R8, R9, 0                         ; R8 = MEM(0x48) = i
+    ADD     R11, R11, R8                      ; sum += i
+
+    J       LOOP
+; }
+END_LOOP:
+
+SW      R9, R11, 4                            ; MEM(0x4C) = sum (0x24)
+SW      R9, R4, 8                             ; MEM(0x50) = 0x00050123
+SW      R9, R5, 12                            ; MEM(0x54) = 0x00770005
@@ -0,0 +1,38 @@
+;
+; Example of virtual memory: 64 bytes pages, single-level page table at 0x3C0
+;
+;  - Pages 0-3 (0x000-0x0FF): code, identity mapped, read & execute
+;  - Page 4 (0x100-0x13F): array, mapped to 0x200, read & write
+;  - Page 5 (0x140-0x17F): constants, mapped to 0x080, read-only
+;
+
+; Page table entries: physical page address | flags (valid: 0x1, read: 0x2, write: 0x4, execute: 0x8)
+@0x03C0: 0B 4B 8B CB 0207 83
+
+; Array (virtual 0x100) and scale factor (virtual 0x140)
+@0x0200: 01 02 03 04 05 06 07 08
+@0x80: 03
+
+LLI     R1, 256                               ; array virtual address (0x100)
+LLI     R2, 320                               ; constants virtual address (0x140)
+LLI     R3, 0                                 ; sum
+LLI     R4, 8                                 ; array length
+LLI     R5, 0                                 ; i loop variable
+
+; for (i = 0; i < n; i+=1) {
+    LOOP:
+
+    BEQ     R5, R4, END_LOOP                  ; break if i == n
+    LW      R6, R1, 0                         ; R6 = array[i]
+    ADD     R3, R3, R6                        ; sum += array[i]
+    ADDI    R1, R1, 4
+    ADDI    R5, R5, 1
+    J       LOOP
+; }
+
+END_LOOP:
+
+LW      R7, R2, 0                             ; R7 = scale
+MUL     R3, R3, R7                            ; R3 = sum * scale
+LLI     R1, 256
+SW      R1, R3, 32                            ; MEM(0x120) = R3, physical 0x220
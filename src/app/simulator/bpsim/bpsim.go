@@ -0,0 +1,110 @@
+package bpsim
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"app/logger"
+	"app/simulator/processor/components/branchhistory"
+	"app/simulator/processor/components/memory"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/branchtrace"
+	"app/simulator/processor/models/instruction"
+	"app/simulator/processor/models/set"
+)
+
+// Outcome of a branch trace replayed through a predictor
+type Result struct {
+	Predictor      config.PredictorType
+	Branches       uint32
+	Mispredictions uint32
+	Instructions   uint32
+	Duration       time.Duration
+	// Accuracy of every window of branches, in trace order
+	WarmUp []float32
+	// Extra lines of the predictors that report their own stats
+	Stats string
+}
+
+func (this *Result) Accuracy() float32 {
+	return 1 - float32(this.Mispredictions)/float32(this.Branches)
+}
+
+func (this *Result) MPKI() float32 {
+	return 1000 * float32(this.Mispredictions) / float32(this.Instructions)
+}
+
+// Branches are replayed in commit order through the same calls the pipeline does: predicted and speculated at fetch,
+// then trained at commit and the history restored on a misprediction. Without a wrong path every update happens
+// before the next prediction, so results may differ slightly from the out-of-order simulation
+func Run(trace *branchtrace.BranchTrace, predictorType config.PredictorType, processorConfig *config.Config, window uint32) (*Result, error) {
+	if predictorType == config.StallPredictor {
+		return nil, errors.New(fmt.Sprintf("Branch predictor %s does not predict, there is nothing to replay", predictorType))
+	}
+	if window == 0 {
+		return nil, errors.New("Warm-up window must have at least 1 branch")
+	}
+	base, err := predictor.NewByName(predictorType, processorConfig)
+	if err != nil {
+		return nil, err
+	}
+	p, err := branchhistory.WithLoopPredictor(processorConfig, base)
+	if err != nil {
+		return nil, err
+	}
+	speculative, isSpeculative := p.(predictor.Speculative)
+
+	result := &Result{
+		Predictor:    predictorType,
+		Instructions: trace.Instructions(),
+		WarmUp:       []float32{},
+	}
+	instructionSet := set.Init()
+	instructions := map[uint32]*instruction.Instruction{}
+	windowMispredictions := uint32(0)
+	start := time.Now()
+	for i, record := range trace.Records() {
+		instruction, exists := instructions[record.Instruction]
+		if !exists {
+			instruction, err = instructionSet.GetInstructionFromBytes(memory.Encode(uint64(record.Instruction), 4, config.BigEndian))
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Failed decoding branch %d at %#04X. %s", i, record.ProgramCounter, err.Error()))
+			}
+			instructions[record.Instruction] = instruction
+		}
+
+		taken := p.Predict(record.ProgramCounter, instruction)
+		if isSpeculative {
+			speculative.Speculate(record.ProgramCounter, taken)
+		}
+		p.Update(record.ProgramCounter, record.Taken, record.Target, taken)
+		result.Branches += 1
+		if taken != record.Taken {
+			result.Mispredictions += 1
+			windowMispredictions += 1
+			if isSpeculative {
+				speculative.Restore()
+			}
+		}
+
+		// Last window may be shorter
+		windowBranches := result.Branches % window
+		if windowBranches == 0 || i == len(trace.Records())-1 {
+			if windowBranches == 0 {
+				windowBranches = window
+			}
+			result.WarmUp = append(result.WarmUp, 1-float32(windowMispredictions)/float32(windowBranches))
+			windowMispredictions = 0
+		}
+	}
+	result.Duration = time.Since(start)
+
+	if reporter, ok := p.(predictor.Reporter); ok {
+		result.Stats = reporter.Stats()
+	}
+	// Predictions are not written to any debug log
+	logger.CleanBuffer()
+	return result, nil
+}
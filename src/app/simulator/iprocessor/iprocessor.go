@@ -1,9 +1,18 @@
 package iprocessor
 
 import (
+	"app/simulator/processor/components/branchtargetbuffer"
+	"app/simulator/processor/components/cache"
 	"app/simulator/processor/components/clock"
+	"app/simulator/processor/components/device"
 	"app/simulator/processor/components/memory"
+	"app/simulator/processor/components/memoryports"
+	"app/simulator/processor/components/mmu"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/components/prefetcher"
 	"app/simulator/processor/config"
+	"app/simulator/processor/models/branchtrace"
+	"app/simulator/processor/models/memorytrace"
 	"app/simulator/processor/models/set"
 )
 
@@ -14,6 +23,8 @@ type IProcessor interface {
 	//       Internals       //
 	///////////////////////////
 	Finish()
+	RaiseFault(fault error)
+	Fault() error
 	InstructionsFetched() []string
 	InstructionsFetchedCounter() uint32
 	InstructionsCompleted() []uint32
@@ -24,9 +35,22 @@ type IProcessor interface {
 	LogEvent(unit string, index uint32, operationId uint32, start uint32)
 	LogEventStart(unit string, index uint32, operationId uint32)
 	LogEventFinish(unit string, index uint32, operationId uint32)
-	LogBranchInstruction(address uint32, conditionalBranch, mispredicted bool, taken bool)
+	LogBranchInstruction(operationId uint32, address uint32, conditionalBranch, mispredicted bool, taken bool, target uint32)
+	LogAtomicInstruction(fence bool, failed bool)
+	LogSerializationStall(cycles uint32)
+	LogRobFullStall()
+	LogStoreForward(partial bool)
+	LogOrderingViolation()
+	LogLoadReplay()
+	LogCodeModification()
+	LogLoadStoreQueueFullStall()
+	LogDeviceStall(cycles uint32)
+	LogFetchBlock(instructions uint32, bytes uint32)
+	LogFetchStall(cycles uint32)
+	LogMemoryInstruction(load bool, store bool)
 	RemoveForwardLogs(operationId uint32)
-	ReachedEnd(bytes []byte) bool
+	ReachedEnd(address uint32, bytes []byte) bool
+	IsCode(address, size uint32) bool
 
 	///////////////////////////
 	//       Metadata        //
@@ -35,13 +59,33 @@ type IProcessor interface {
 	InstructionsSet() set.Set
 	Config() *config.Config
 	DataMemory() *memory.Memory
+	DataCache() *cache.Cache
+	InstructionCache() *cache.Cache
+	DataMemoryLevel() cache.Level
+	InstructionMemoryLevel() cache.Level
+	MemoryLevels() []cache.Level
+	Devices() *device.Bus
+	MMU() *mmu.MMU
+	DataPrefetcher() *prefetcher.Prefetcher
+	MemoryPorts() *memoryports.MemoryPorts
+	MemoryTrace() *memorytrace.MemoryTrace
+	BranchTrace() *branchtrace.BranchTrace
 	InstructionsMemory() *memory.Memory
 	RegistersMemory() *memory.Memory
 	ProgramCounter() uint32
 	SetProgramCounter(value uint32)
 	IncrementProgramCounter(offset int32)
-	SetPredictorBits(bits uint32)
-	GetBranchStateByAddress(address uint32) (uint32, bool)
+	Predictor() predictor.Predictor
+	BranchTargetBuffer() *branchtargetbuffer.BranchTargetBuffer
+	PerformanceCounter(index uint32) uint32
+	SetReservation(address uint32)
+	CheckReservation(address uint32) bool
+	ClearReservation()
+	PushHardwareLoop(start uint32, end uint32, count uint32)
+	HardwareLoopDepthReached() bool
+	CommitHardwareLoop(nextAddress uint32) (uint32, bool)
+	NextHardwareLoopAddress(nextAddress uint32) (uint32, bool)
+	RestoreHardwareLoops()
 	SpeculativeJumps() uint32
 	AddSpeculativeJump()
 	DecrementSpeculativeJump()
@@ -4,14 +4,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/codegangsta/cli"
 
 	"app/logger"
+	"app/simulator/bpsim"
 	"app/simulator/processor"
 	"app/simulator/processor/config"
 	"app/simulator/processor/consts"
+	"app/simulator/processor/models/branchreport"
+	"app/simulator/processor/models/branchtrace"
+	"app/simulator/processor/models/memorytrace"
 	"app/simulator/translator"
 )
 
@@ -48,6 +53,20 @@ func main() {
 					Value: "",
 					Usage: "Processor config filename, a valid config filename is required",
 				},
+				cli.StringFlag{
+					Name:  "memory-trace",
+					Value: "",
+					Usage: "Trace every load and store into the output folder, in din (text, Dinero) or binary format",
+				},
+				cli.StringFlag{
+					Name:  "branch-profile",
+					Value: "",
+					Usage: "Set the hint bits of the conditional branches from the branches.csv report of a previous run",
+				},
+				cli.BoolFlag{
+					Name:  "branch-trace",
+					Usage: "Trace every committed conditional branch into the output folder, to be replayed with bpsim",
+				},
 				cli.IntFlag{
 					Name:  "max-cycles",
 					Value: 3000,
@@ -55,6 +74,29 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:        "bpsim",
+			Usage:       "bpsim <branch-trace>",
+			Description: "replay a branch trace recorded with run --branch-trace through one or more branch predictors",
+			Action:      bpsimCommand,
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "c, config-filename",
+					Value: &cli.StringSlice{},
+					Usage: "Processor config filename with the predictor type and its parameters, it can be repeated to compare them",
+				},
+				cli.StringFlag{
+					Name:  "p, predictor",
+					Value: "",
+					Usage: "Comma separated predictor types replayed with the parameters of a single config, instead of its own type",
+				},
+				cli.IntFlag{
+					Name:  "window",
+					Value: 100,
+					Usage: "Branches per point of the warm-up curve",
+				},
+			},
+		},
 	}
 
 	app.Run(os.Args)
@@ -94,14 +136,24 @@ func runCommand(c *cli.Context) {
 	}
 	logger.Print(" => Configuration file: %s", configFilename)
 
-	err = runProgram(assemblyFilename, c.Bool("step-by-step"), outputFolder, cfg, uint32(c.Int("max-cycles")))
+	var profile []*branchreport.Record
+	if c.String("branch-profile") != "" {
+		profile, err = branchreport.Load(c.String("branch-profile"))
+		if err != nil {
+			logger.Error("Failed loading branch profile. %s", err.Error())
+			os.Exit(1)
+		}
+		logger.Print(" => Branch profile: %s", c.String("branch-profile"))
+	}
+
+	err = runProgram(assemblyFilename, c.Bool("step-by-step"), outputFolder, cfg, uint32(c.Int("max-cycles")), c.String("memory-trace"), c.Bool("branch-trace"), profile)
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
 	}
 }
 
-func runProgram(assemblyFilename string, interactive bool, outputFolder string, config *config.Config, maxCycles uint32) error {
+func runProgram(assemblyFilename string, interactive bool, outputFolder string, config *config.Config, maxCycles uint32, memoryTrace string, branchTrace bool, profile []*branchreport.Record) error {
 
 	err := os.MkdirAll(outputFolder, 0777)
 	if err != nil {
@@ -109,7 +161,7 @@ func runProgram(assemblyFilename string, interactive bool, outputFolder string,
 	}
 
 	// Translate assembly file to hex file
-	hexFilename, err := translator.TranslateFromFile(assemblyFilename, filepath.Join(outputFolder, "assembly.hex"))
+	hexFilename, err := translator.TranslateFromFile(assemblyFilename, filepath.Join(outputFolder, "assembly.hex"), config.CompressedInstructions(), config.CodeBase(), config.InstructionsEndianness(), config.ArchitectureSize(), profile)
 	if err != nil {
 		return err
 	}
@@ -119,6 +171,15 @@ func runProgram(assemblyFilename string, interactive bool, outputFolder string,
 	if err != nil {
 		return err
 	}
+	if memoryTrace != "" {
+		err = p.EnableMemoryTrace(memorytrace.Format(memoryTrace))
+		if err != nil {
+			return err
+		}
+	}
+	if branchTrace {
+		p.EnableBranchTrace()
+	}
 
 	// Start simulation
 	p.Start()
@@ -141,8 +202,85 @@ func runProgram(assemblyFilename string, interactive bool, outputFolder string,
 		}
 	}
 
-	logger.Print(p.Stats())
-	return p.SaveOutputFiles(outputFolder)
+	logger.Print("%s", p.Stats())
+	err = p.SaveOutputFiles(outputFolder)
+	if err != nil {
+		return err
+	}
+	// Output files are saved up to the operation that raised the fault
+	return p.Fault()
+}
+
+// Predictors are registered by the packages imported from the processor
+func bpsimCommand(c *cli.Context) {
+
+	printHeader()
+
+	if len(c.Args()) != 1 {
+		logger.Error("Expecting <branch-trace> and got %d parameters", len(c.Args()))
+		os.Exit(1)
+	}
+
+	trace, err := branchtrace.Load(c.Args()[0])
+	if err != nil {
+		logger.Error("Failed loading branch trace. %s", err.Error())
+		os.Exit(1)
+	}
+	logger.Print(" => Branch trace: %s (%d branches, %d instructions)", c.Args()[0], len(trace.Records()), trace.Instructions())
+
+	configFilenames := c.StringSlice("config-filename")
+	if len(configFilenames) == 0 {
+		logger.Error("Configuration file not provided, please provide a valid configuration file")
+		os.Exit(1)
+	}
+	if c.String("predictor") != "" && len(configFilenames) > 1 {
+		logger.Error("Predictor types are replayed with a single configuration file and got %d", len(configFilenames))
+		os.Exit(1)
+	}
+
+	for _, configFilename := range configFilenames {
+		cfg, err := config.Load(configFilename)
+		if err != nil {
+			logger.Error("Failed loading config %s. %s", configFilename, err.Error())
+			os.Exit(1)
+		}
+		predictorTypes := []config.PredictorType{cfg.BranchPredictorType()}
+		if c.String("predictor") != "" {
+			predictorTypes = []config.PredictorType{}
+			for _, name := range strings.Split(c.String("predictor"), ",") {
+				predictorTypes = append(predictorTypes, config.PredictorType(strings.TrimSpace(name)))
+			}
+		}
+		for _, predictorType := range predictorTypes {
+			result, err := bpsim.Run(trace, predictorType, cfg, uint32(c.Int("window")))
+			if err != nil {
+				logger.Error(err.Error())
+				os.Exit(1)
+			}
+			printBpsimResult(result, configFilename, uint32(c.Int("window")))
+		}
+	}
+}
+
+func printBpsimResult(result *bpsim.Result, configFilename string, window uint32) {
+	logger.Print("\n Predictor %s (%s):\n", result.Predictor, configFilename)
+	logger.Print(" => Branches: %d", result.Branches)
+	logger.Print(" => Mispredictions: %d", result.Mispredictions)
+	logger.Print(" => Accuracy: %3.2f%%", 100*result.Accuracy())
+	logger.Print(" => Mispredictions per Kilo Instructions (MPKI): %3.2f", result.MPKI())
+	logger.Print(" => Replay duration: %3.3f ms", float64(result.Duration.Nanoseconds())/1e6)
+	if result.Stats != "" {
+		logger.Print("%s", strings.TrimRight(result.Stats, "\n"))
+	}
+	logger.Print(" => Warm-up (accuracy every %d branches):", window)
+	for i, accuracy := range result.WarmUp {
+		// Last point covers the remaining branches
+		branches := uint32(i+1) * window
+		if branches > result.Branches {
+			branches = result.Branches
+		}
+		logger.Print("    %6d %6.2f%% %s", branches, 100*accuracy, strings.Repeat("#", int(50*accuracy)))
+	}
 }
 
 func getFileName(filename string) string {
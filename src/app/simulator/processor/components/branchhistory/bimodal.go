@@ -0,0 +1,102 @@
+package branchhistory
+
+import (
+	"fmt"
+	"sync"
+
+	"app/logger"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/instruction"
+)
+
+func init() {
+	predictor.Register(config.OneBitPredictor, newBimodalFactory(1))
+	predictor.Register(config.TwoBitPredictor, newBimodalFactory(2))
+}
+
+// One-bit and two-bit predictors: a counter per branch, unbounded unless a finite branch history table is set
+type Bimodal struct {
+	*bimodal
+}
+
+type bimodal struct {
+	predictorBits uint32
+	states        map[uint32]uint32
+	table         *Table
+	lock          sync.Mutex
+}
+
+func newBimodalFactory(predictorBits uint32) predictor.Factory {
+	return func(processorConfig *config.Config) (predictor.Predictor, error) {
+		bimodal := &Bimodal{
+			&bimodal{
+				predictorBits: predictorBits,
+				states:        map[uint32]uint32{},
+			},
+		}
+		if processorConfig.BranchHistoryTable() != nil {
+			table, err := NewTable(processorConfig.BranchHistoryTable(), processorConfig.InstructionAlignment())
+			if err != nil {
+				return nil, err
+			}
+			bimodal.table = table
+		}
+		return bimodal, nil
+	}
+}
+
+func (this *Bimodal) PredictorBits() uint32 {
+	return this.bimodal.predictorBits
+}
+
+func (this *Bimodal) Table() *Table {
+	return this.bimodal.table
+}
+
+func (this *Bimodal) Stats() string {
+	if this.Table() == nil {
+		return ""
+	}
+	str := fmt.Sprintf(" => Aliasing Mispredictions: %d\n", this.Table().AliasingMispredictions())
+	str += fmt.Sprintf(" => Branch Predictor Storage: %d bits\n", this.Table().StorageBits(this.PredictorBits()))
+	return str
+}
+
+func (this *Bimodal) State(pc uint32) (uint32, bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.Table() != nil {
+		return this.Table().State(pc)
+	}
+	state, exists := this.bimodal.states[pc]
+	return state, exists
+}
+
+func (this *Bimodal) Predict(pc uint32, instruction *instruction.Instruction) bool {
+	state, exists := this.State(pc)
+	if !exists {
+		logger.Collect(" => [BP0]: No history for address %#04X", pc)
+		return false
+	}
+	totalStates := uint32(1) << this.PredictorBits()
+	taken := state >= totalStates/2
+	logger.Collect(" => [BP0]: Address %#04X, Total States: %d, State: %d ,Taken: %v", pc, totalStates, state, taken)
+	return taken
+}
+
+func (this *Bimodal) Update(pc uint32, taken bool, target uint32, predicted bool) {
+	state, _ := this.State(pc)
+	nextState := GetNextState(state, this.PredictorBits(), taken)
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.Table() != nil {
+		if predicted != taken {
+			this.Table().LogMisprediction(pc)
+		}
+		this.Table().SetState(pc, nextState)
+		return
+	}
+	this.bimodal.states[pc] = nextState
+}
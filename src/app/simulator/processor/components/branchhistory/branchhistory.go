@@ -0,0 +1,234 @@
+package branchhistory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"app/logger"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/instruction"
+)
+
+// Saturating counters of the pattern table
+const COUNTER_BITS = 2
+
+func init() {
+	for _, predictorType := range []config.PredictorType{config.GsharePredictor, config.GselectPredictor, config.LocalPAgPredictor, config.LocalPApPredictor} {
+		predictor.Register(predictorType, newFactory(predictorType))
+	}
+}
+
+func newFactory(predictorType config.PredictorType) predictor.Factory {
+	return func(processorConfig *config.Config) (predictor.Predictor, error) {
+		bh, err := New(predictorType, processorConfig.BranchHistory(), processorConfig.InstructionAlignment())
+		if err != nil {
+			return nil, err
+		}
+		return bh, nil
+	}
+}
+
+type BranchHistory struct {
+	*branchHistory
+}
+
+type branchHistory struct {
+	predictorType config.PredictorType
+	config        *config.BranchHistoryConfig
+	alignment     uint32
+	indexBits     uint32
+	lock          sync.Mutex
+
+	// Committed histories are shifted at commit, speculative ones at fetch and restored on recovery
+	globalHistory             uint32
+	speculativeGlobalHistory  uint32
+	localHistories            []uint32
+	speculativeLocalHistories []uint32
+	patternTable              []uint32
+}
+
+func New(predictorType config.PredictorType, historyConfig *config.BranchHistoryConfig, alignment uint32) (*BranchHistory, error) {
+	if historyConfig == nil {
+		return nil, errors.New(fmt.Sprintf("Branch predictor %s requires a branch_history object", predictorType))
+	}
+	indexBits, ok := log2(historyConfig.PatternTableEntries)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Pattern table entries must be a power of 2 (%d)", historyConfig.PatternTableEntries))
+	}
+	if historyConfig.HistoryLength == 0 || historyConfig.HistoryLength > indexBits {
+		return nil, errors.New(fmt.Sprintf("History length must be between 1 and the %d index bits of the pattern table (%d)", indexBits, historyConfig.HistoryLength))
+	}
+	bh := &BranchHistory{
+		&branchHistory{
+			predictorType: predictorType,
+			config:        historyConfig,
+			alignment:     alignment,
+			indexBits:     indexBits,
+			patternTable:  make([]uint32, historyConfig.PatternTableEntries),
+		},
+	}
+	if predictorType.IsLocalHistory() {
+		if _, ok := log2(historyConfig.LocalHistoryEntries); !ok {
+			return nil, errors.New(fmt.Sprintf("Local history entries must be a power of 2 (%d)", historyConfig.LocalHistoryEntries))
+		}
+		bh.localHistories = make([]uint32, historyConfig.LocalHistoryEntries)
+		bh.speculativeLocalHistories = make([]uint32, historyConfig.LocalHistoryEntries)
+	}
+	return bh, nil
+}
+
+// Saturating counter of predictorBits moved towards taken or not taken
+func GetNextState(currentState uint32, predictorBits uint32, taken bool) uint32 {
+	maxValue := uint32(math.Exp2(float64(predictorBits))) - 1
+	if taken {
+		if currentState == maxValue {
+			return currentState
+		}
+		return currentState + 1
+	} else {
+		if currentState == 0 {
+			return currentState
+		}
+		return currentState - 1
+	}
+}
+
+func (this *BranchHistory) Config() *config.BranchHistoryConfig {
+	return this.branchHistory.config
+}
+
+func (this *BranchHistory) GlobalHistory() uint32 {
+	return this.branchHistory.globalHistory
+}
+
+// Pattern table counters and the history registers
+func (this *BranchHistory) StorageBits() uint32 {
+	historyBits := this.Config().HistoryLength
+	if this.predictorType.IsLocalHistory() {
+		historyBits *= uint32(len(this.localHistories))
+	}
+	return uint32(len(this.patternTable))*COUNTER_BITS + historyBits
+}
+
+func (this *BranchHistory) Stats() string {
+	return fmt.Sprintf(" => Branch Predictor Storage: %d bits\n", this.StorageBits())
+}
+
+func (this *BranchHistory) Predict(pc uint32, instruction *instruction.Instruction) bool {
+	index := this.Index(pc, true)
+	state := this.State(index)
+	taken := this.Taken(state)
+	logger.Collect(" => [BP0]: Address %#04X, Pattern Index: %d, State: %d ,Taken: %v", pc, index, state, taken)
+	return taken
+}
+
+// Trained with the committed history, the same one the branch was predicted with
+func (this *BranchHistory) Update(pc uint32, taken bool, target uint32, predicted bool) {
+	index := this.Index(pc, false)
+	this.SetState(index, GetNextState(this.State(index), COUNTER_BITS, taken))
+	this.Commit(pc, taken)
+}
+
+// Pattern table entry of a branch, with the speculative history at fetch or the committed one at commit
+func (this *BranchHistory) Index(address uint32, speculative bool) uint32 {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	pc := address / this.alignment
+	history := this.history(pc, speculative) & mask(this.Config().HistoryLength)
+	historyLength := this.Config().HistoryLength
+
+	index := uint32(0)
+	switch this.predictorType {
+	case config.GsharePredictor:
+		index = pc ^ history
+	case config.GselectPredictor, config.LocalPApPredictor:
+		index = pc<<historyLength | history
+	case config.LocalPAgPredictor:
+		index = history
+	}
+	return index & mask(this.indexBits)
+}
+
+func (this *BranchHistory) State(index uint32) uint32 {
+	return this.branchHistory.patternTable[index]
+}
+
+func (this *BranchHistory) SetState(index uint32, state uint32) {
+	this.branchHistory.patternTable[index] = state
+}
+
+func (this *BranchHistory) Taken(state uint32) bool {
+	return state >= 1<<(COUNTER_BITS-1)
+}
+
+// Shifts the outcome of a fetched branch into the speculative history
+func (this *BranchHistory) Speculate(address uint32, taken bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.shift(address, taken, &this.branchHistory.speculativeGlobalHistory, this.branchHistory.speculativeLocalHistories)
+}
+
+// Shifts the outcome of a committed branch into the committed history
+func (this *BranchHistory) Commit(address uint32, taken bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.shift(address, taken, &this.branchHistory.globalHistory, this.branchHistory.localHistories)
+}
+
+// Repairs the speculative history on a recovery, wrong-path branches are dropped
+func (this *BranchHistory) Restore() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.branchHistory.speculativeGlobalHistory = this.branchHistory.globalHistory
+	copy(this.branchHistory.speculativeLocalHistories, this.branchHistory.localHistories)
+}
+
+func (this *BranchHistory) history(pc uint32, speculative bool) uint32 {
+	if this.predictorType.IsLocalHistory() {
+		entry := pc % uint32(len(this.localHistories))
+		if speculative {
+			return this.speculativeLocalHistories[entry]
+		}
+		return this.localHistories[entry]
+	}
+	if speculative {
+		return this.speculativeGlobalHistory
+	}
+	return this.globalHistory
+}
+
+func (this *BranchHistory) shift(address uint32, taken bool, globalHistory *uint32, localHistories []uint32) {
+	bit := uint32(0)
+	if taken {
+		bit = 1
+	}
+	if this.predictorType.IsLocalHistory() {
+		entry := (address / this.alignment) % uint32(len(localHistories))
+		localHistories[entry] = localHistories[entry]<<1 | bit
+		return
+	}
+	*globalHistory = *globalHistory<<1 | bit
+}
+
+func mask(bits uint32) uint32 {
+	if bits >= 32 {
+		return ^uint32(0)
+	}
+	return 1<<bits - 1
+}
+
+func log2(value uint32) (uint32, bool) {
+	if value == 0 || value&(value-1) != 0 {
+		return 0, false
+	}
+	bits := uint32(0)
+	for value > 1 {
+		value >>= 1
+		bits += 1
+	}
+	return bits, true
+}
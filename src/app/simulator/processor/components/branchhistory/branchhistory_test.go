@@ -0,0 +1,59 @@
+package branchhistory
+
+import (
+	"testing"
+
+	"app/simulator/processor/config"
+)
+
+func TestIndex(t *testing.T) {
+	// Address: 0x0C / 4 = 3
+	// Bin: 0011, history: 0101
+	historyConfig := &config.BranchHistoryConfig{HistoryLength: 4, PatternTableEntries: 256, LocalHistoryEntries: 16}
+	indexes := []struct {
+		predictorType config.PredictorType
+		index         uint32
+	}{
+		{config.GsharePredictor, 6},    // 0011 ^ 0101
+		{config.GselectPredictor, 53},  // 0011 0101
+		{config.LocalPAgPredictor, 5},  // 0101
+		{config.LocalPApPredictor, 53}, // 0011 0101
+	}
+	for _, expected := range indexes {
+		bh, err := New(expected.predictorType, historyConfig, 4)
+		if err != nil {
+			t.Fatalf("%s failed: %s", expected.predictorType, err.Error())
+		}
+		for _, taken := range []bool{false, true, false, true} {
+			bh.Commit(0x0C, taken)
+		}
+		index := bh.Index(0x0C, false)
+		if index != expected.index {
+			t.Errorf("%s index expected %d - Got %d", expected.predictorType, expected.index, index)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	historyConfig := &config.BranchHistoryConfig{HistoryLength: 4, PatternTableEntries: 256}
+	bh, err := New(config.GsharePredictor, historyConfig, 4)
+	if err != nil {
+		t.Fatalf("gshare failed: %s", err.Error())
+	}
+	// Branch fetched then committed: 1
+	bh.Speculate(0x0C, true)
+	bh.Commit(0x0C, true)
+
+	// Wrong path: 111, index 0011 ^ 0111
+	bh.Speculate(0x0C, true)
+	bh.Speculate(0x0C, true)
+	if index := bh.Index(0x0C, true); index != 4 {
+		t.Errorf("Speculative index expected %d - Got %d", 4, index)
+	}
+
+	// Back to the committed 1, index 0011 ^ 0001
+	bh.Restore()
+	if index := bh.Index(0x0C, true); index != 2 {
+		t.Errorf("Restored index expected %d - Got %d", 2, index)
+	}
+}
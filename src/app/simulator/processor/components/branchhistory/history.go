@@ -0,0 +1,80 @@
+package branchhistory
+
+import (
+	"sync"
+)
+
+// Global history longer than a register, the most recent outcome first
+// Shifted speculatively at fetch and at commit, the speculative one is restored from the committed one on recovery
+type GlobalHistory struct {
+	*globalHistory
+}
+
+type globalHistory struct {
+	lock        sync.Mutex
+	committed   []bool
+	speculative []bool
+}
+
+func NewGlobalHistory(length uint32) *GlobalHistory {
+	return &GlobalHistory{
+		&globalHistory{
+			committed:   make([]bool, length),
+			speculative: make([]bool, length),
+		},
+	}
+}
+
+func (this *GlobalHistory) Length() uint32 {
+	return uint32(len(this.globalHistory.committed))
+}
+
+// Copy of the speculative history at fetch or the committed one at commit
+func (this *GlobalHistory) Bits(speculative bool) []bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if speculative {
+		return append([]bool{}, this.globalHistory.speculative...)
+	}
+	return append([]bool{}, this.globalHistory.committed...)
+}
+
+func (this *GlobalHistory) Speculate(taken bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	shiftBits(this.globalHistory.speculative, taken)
+}
+
+func (this *GlobalHistory) Commit(taken bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	shiftBits(this.globalHistory.committed, taken)
+}
+
+func (this *GlobalHistory) Restore() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	copy(this.globalHistory.speculative, this.globalHistory.committed)
+}
+
+func shiftBits(bits []bool, taken bool) {
+	if len(bits) == 0 {
+		return
+	}
+	copy(bits[1:], bits[:len(bits)-1])
+	bits[0] = taken
+}
+
+// Folds the first length outcomes of the history into a value of width bits
+func fold(bits []bool, length uint32, width uint32) uint32 {
+	if width == 0 {
+		return 0
+	}
+	value := uint32(0)
+	for i := uint32(0); i < length && i < uint32(len(bits)); i++ {
+		if bits[i] {
+			value ^= 1 << (i % width)
+		}
+	}
+	return value
+}
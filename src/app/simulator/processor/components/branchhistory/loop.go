@@ -0,0 +1,226 @@
+package branchhistory
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"app/logger"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/instruction"
+)
+
+const (
+	// Iteration counters of an entry, longer loops are dropped
+	LOOP_ITERATION_BITS  = 10
+	LOOP_CONFIDENCE_BITS = 4
+)
+
+// Loop predictor: learns how many times a branch goes the same way before the loop exits,
+// and overrides the base predictor for the branches whose trip count is confident
+type LoopPredictor struct {
+	*loopPredictor
+}
+
+type loopPredictor struct {
+	config    *config.LoopPredictorConfig
+	alignment uint32
+	base      predictor.Predictor
+	entries   []loopEntry
+	lock      sync.Mutex
+
+	// stats
+	predictions uint32
+	correct     uint32
+}
+
+type loopEntry struct {
+	valid      bool
+	address    uint32
+	direction  bool
+	trip       uint32
+	current    uint32
+	confidence uint32
+	// Iterations fetched, repaired from the committed ones on every recovery
+	speculative uint32
+}
+
+// Base predictor alone when the config has no loop predictor
+func WithLoopPredictor(processorConfig *config.Config, base predictor.Predictor) (predictor.Predictor, error) {
+	if processorConfig.LoopPredictor() == nil || base == nil {
+		return base, nil
+	}
+	loop, err := NewLoopPredictor(processorConfig.LoopPredictor(), processorConfig.InstructionAlignment(), base)
+	if err != nil {
+		return nil, err
+	}
+	return loop, nil
+}
+
+func NewLoopPredictor(loopConfig *config.LoopPredictorConfig, alignment uint32, base predictor.Predictor) (*LoopPredictor, error) {
+	if _, ok := log2(loopConfig.Entries); !ok {
+		return nil, errors.New(fmt.Sprintf("Loop predictor entries must be a power of 2 (%d)", loopConfig.Entries))
+	}
+	if loopConfig.ConfidenceThreshold() > mask(LOOP_CONFIDENCE_BITS) {
+		return nil, errors.New(fmt.Sprintf("Loop predictor confidence must be at most %d (%d)", mask(LOOP_CONFIDENCE_BITS), loopConfig.ConfidenceThreshold()))
+	}
+	return &LoopPredictor{
+		&loopPredictor{
+			config:    loopConfig,
+			alignment: alignment,
+			base:      base,
+			entries:   make([]loopEntry, loopConfig.Entries),
+		},
+	}, nil
+}
+
+func (this *LoopPredictor) Config() *config.LoopPredictorConfig {
+	return this.loopPredictor.config
+}
+
+func (this *LoopPredictor) Base() predictor.Predictor {
+	return this.loopPredictor.base
+}
+
+// Committed branches predicted by the loop predictor instead of the base one
+func (this *LoopPredictor) Predictions() uint32 {
+	return this.loopPredictor.predictions
+}
+
+func (this *LoopPredictor) Correct() uint32 {
+	return this.loopPredictor.correct
+}
+
+// Address, valid and direction bits, the three iteration counters and the confidence
+func (this *LoopPredictor) StorageBits() uint32 {
+	return uint32(len(this.loopPredictor.entries)) * (32 + 2 + 3*LOOP_ITERATION_BITS + LOOP_CONFIDENCE_BITS)
+}
+
+func (this *LoopPredictor) Stats() string {
+	str := ""
+	if reporter, ok := this.Base().(predictor.Reporter); ok {
+		str += reporter.Stats()
+	}
+	str += fmt.Sprintf(" => Loop Predictor Predictions: %d (%d correct)\n", this.Predictions(), this.Correct())
+	str += fmt.Sprintf(" => Loop Predictor Storage: %d bits\n", this.StorageBits())
+	return str
+}
+
+func (this *LoopPredictor) Predict(pc uint32, instruction *instruction.Instruction) bool {
+	taken := this.Base().Predict(pc, instruction)
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	entry := this.entry(pc)
+	if entry == nil || !this.confident(entry) {
+		return taken
+	}
+	taken = this.taken(entry, entry.speculative)
+	logger.Collect(" => [BP0]: Address %#04X, Loop Iteration: %d of %d, Taken: %v", pc, entry.speculative, entry.trip, taken)
+	return taken
+}
+
+func (this *LoopPredictor) Speculate(pc uint32, taken bool) {
+	if speculative, ok := this.Base().(predictor.Speculative); ok {
+		speculative.Speculate(pc, taken)
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if entry := this.entry(pc); entry != nil {
+		if taken == entry.direction {
+			entry.speculative += 1
+		} else {
+			entry.speculative = 0
+		}
+	}
+}
+
+func (this *LoopPredictor) Restore() {
+	if speculative, ok := this.Base().(predictor.Speculative); ok {
+		speculative.Restore()
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for i := range this.loopPredictor.entries {
+		this.loopPredictor.entries[i].speculative = this.loopPredictor.entries[i].current
+	}
+}
+
+// A trip count is confident once the loop exits after the same number of iterations a few times in a row
+func (this *LoopPredictor) Update(pc uint32, taken bool, target uint32, predicted bool) {
+	this.Base().Update(pc, taken, target, predicted)
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	entry := &this.loopPredictor.entries[this.index(pc)]
+	if !entry.valid || entry.address != pc {
+		// Confident loops are only replaced once they age
+		if entry.valid && entry.confidence > 0 {
+			entry.confidence -= 1
+			return
+		}
+		*entry = loopEntry{valid: true, address: pc, direction: taken, current: 1, speculative: 1}
+		return
+	}
+
+	if this.confident(entry) {
+		this.loopPredictor.predictions += 1
+		if this.taken(entry, entry.current) == taken {
+			this.loopPredictor.correct += 1
+		}
+	}
+
+	if taken == entry.direction {
+		entry.current += 1
+		if entry.current > mask(LOOP_ITERATION_BITS) {
+			entry.valid = false
+		} else if entry.trip > 0 && entry.current > entry.trip {
+			// Longer than the trip count learnt
+			entry.trip, entry.confidence = 0, 0
+		}
+		return
+	}
+
+	switch {
+	case entry.current == 0:
+		// Two exits in a row, the loop goes the other way
+		entry.direction, entry.trip, entry.confidence = taken, 0, 0
+		entry.current, entry.speculative = 1, 1
+		return
+	case entry.current == entry.trip:
+		if entry.confidence < this.Config().ConfidenceThreshold() {
+			entry.confidence += 1
+		}
+	default:
+		entry.trip, entry.confidence = entry.current, 0
+	}
+	entry.current = 0
+}
+
+func (this *LoopPredictor) index(pc uint32) uint32 {
+	return (pc / this.loopPredictor.alignment) % uint32(len(this.loopPredictor.entries))
+}
+
+// Entry of the branch, nil when another branch holds it
+func (this *LoopPredictor) entry(pc uint32) *loopEntry {
+	entry := &this.loopPredictor.entries[this.index(pc)]
+	if !entry.valid || entry.address != pc {
+		return nil
+	}
+	return entry
+}
+
+func (this *LoopPredictor) confident(entry *loopEntry) bool {
+	return entry.trip > 0 && entry.confidence >= this.Config().ConfidenceThreshold()
+}
+
+// The loop goes on until the iterations reach the trip count
+func (this *LoopPredictor) taken(entry *loopEntry, iterations uint32) bool {
+	if iterations < entry.trip {
+		return entry.direction
+	}
+	return !entry.direction
+}
@@ -0,0 +1,58 @@
+package branchhistory
+
+import (
+	"testing"
+
+	"app/simulator/processor/config"
+)
+
+// Loop branch at 0x40 taken 9 times then not taken on the exit, a longer pattern than the 4 bits of gshare history
+func runLoops(t *testing.T, loops int) *LoopPredictor {
+	base, err := New(config.GsharePredictor, &config.BranchHistoryConfig{HistoryLength: 4, PatternTableEntries: 256}, 4)
+	if err != nil {
+		t.Fatalf("gshare failed: %s", err.Error())
+	}
+	loop, err := NewLoopPredictor(&config.LoopPredictorConfig{Entries: 16}, 4, base)
+	if err != nil {
+		t.Fatalf("Loop predictor failed: %s", err.Error())
+	}
+	for i := 0; i < loops*10; i++ {
+		taken := i%10 != 9
+		predicted := loop.Predict(0x40, nil)
+		loop.Speculate(0x40, taken)
+		loop.Update(0x40, taken, 0x20, predicted)
+	}
+	return loop
+}
+
+func TestTripCount(t *testing.T) {
+	// Trip count learnt on the first exit, confident after two more exits with the same count
+	loop := runLoops(t, 3)
+	for i := 0; i < 10; i++ {
+		taken := i != 9
+		predicted := loop.Predict(0x40, nil)
+		if predicted != taken {
+			t.Errorf("Iteration %d expected taken %v - Got %v", i+1, taken, predicted)
+		}
+		loop.Speculate(0x40, taken)
+		loop.Update(0x40, taken, 0x20, predicted)
+	}
+	if loop.Predictions() != 10 || loop.Correct() != 10 {
+		t.Errorf("Loop predictions expected %d correct of %d - Got %d of %d", 10, 10, loop.Correct(), loop.Predictions())
+	}
+}
+
+func TestReplacement(t *testing.T) {
+	// 0x80 takes the same entry of the 16 entries, the loop keeps it until its confidence of 2 ages
+	loop := runLoops(t, 4)
+	for i := 0; i < 2; i++ {
+		loop.Update(0x80, true, 0x60, false)
+		if loop.entry(0x40) == nil {
+			t.Errorf("Entry expected on %#04X after %d updates of %#04X", 0x40, i+1, 0x80)
+		}
+	}
+	loop.Update(0x80, true, 0x60, false)
+	if loop.entry(0x80) == nil {
+		t.Errorf("Entry expected on %#04X once the loop aged", 0x80)
+	}
+}
@@ -0,0 +1,148 @@
+package branchhistory
+
+import (
+	"errors"
+	"fmt"
+
+	"app/logger"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/instruction"
+)
+
+func init() {
+	predictor.Register(config.PerceptronPredictor, func(processorConfig *config.Config) (predictor.Predictor, error) {
+		perceptron, err := NewPerceptron(processorConfig.Perceptron(), processorConfig.InstructionAlignment())
+		if err != nil {
+			return nil, err
+		}
+		return perceptron, nil
+	})
+}
+
+// Perceptron predictor: the sign of the dot product of the weights of a branch and its global history
+type Perceptron struct {
+	*perceptron
+}
+
+type perceptron struct {
+	config    *config.PerceptronConfig
+	alignment uint32
+	history   *GlobalHistory
+	weights   [][]int32
+	maxWeight int32
+	minWeight int32
+
+	// stats
+	trainings uint32
+}
+
+func NewPerceptron(perceptronConfig *config.PerceptronConfig, alignment uint32) (*Perceptron, error) {
+	if perceptronConfig == nil {
+		return nil, errors.New("Branch predictor perceptron requires a perceptron object")
+	}
+	if perceptronConfig.Entries == 0 || perceptronConfig.HistoryLength == 0 {
+		return nil, errors.New(fmt.Sprintf("Perceptron needs at least one entry and one history bit (%s)", perceptronConfig.ToString()))
+	}
+	if perceptronConfig.WeightBits < 2 || perceptronConfig.WeightBits > 16 {
+		return nil, errors.New(fmt.Sprintf("Perceptron weight bits must be between 2 and 16 (%d)", perceptronConfig.WeightBits))
+	}
+	weights := make([][]int32, perceptronConfig.Entries)
+	for i := range weights {
+		// First weight is the bias
+		weights[i] = make([]int32, perceptronConfig.HistoryLength+1)
+	}
+	return &Perceptron{
+		&perceptron{
+			config:    perceptronConfig,
+			alignment: alignment,
+			history:   NewGlobalHistory(perceptronConfig.HistoryLength),
+			weights:   weights,
+			maxWeight: 1<<(perceptronConfig.WeightBits-1) - 1,
+			minWeight: -1 << (perceptronConfig.WeightBits - 1),
+		},
+	}, nil
+}
+
+func (this *Perceptron) Config() *config.PerceptronConfig {
+	return this.perceptron.config
+}
+
+func (this *Perceptron) Trainings() uint32 {
+	return this.perceptron.trainings
+}
+
+// Weights (with the bias) of every perceptron and the history register
+func (this *Perceptron) StorageBits() uint32 {
+	return this.Config().Entries*(this.Config().HistoryLength+1)*this.Config().WeightBits + this.Config().HistoryLength
+}
+
+func (this *Perceptron) Stats() string {
+	str := fmt.Sprintf(" => Perceptron Trainings: %d\n", this.Trainings())
+	str += fmt.Sprintf(" => Branch Predictor Storage: %d bits\n", this.StorageBits())
+	return str
+}
+
+func (this *Perceptron) Predict(pc uint32, instruction *instruction.Instruction) bool {
+	output := this.output(pc, this.perceptron.history.Bits(true))
+	logger.Collect(" => [BP0]: Address %#04X, Perceptron Output: %d, Taken: %v", pc, output, output >= 0)
+	return output >= 0
+}
+
+func (this *Perceptron) Speculate(pc uint32, taken bool) {
+	this.perceptron.history.Speculate(taken)
+}
+
+func (this *Perceptron) Restore() {
+	this.perceptron.history.Restore()
+}
+
+// Trained at commit with the committed history on a misprediction or while the output is below the threshold
+func (this *Perceptron) Update(address uint32, taken bool, target uint32, predicted bool) {
+	history := this.perceptron.history.Bits(false)
+	output := this.output(address, history)
+	if (output >= 0) != taken || abs(output) <= int32(this.Config().TrainingThreshold()) {
+		weights := this.perceptron.weights[this.index(address)]
+		weights[0] = this.train(weights[0], taken)
+		for i, bit := range history {
+			weights[i+1] = this.train(weights[i+1], bit == taken)
+		}
+		this.perceptron.trainings += 1
+	}
+	this.perceptron.history.Commit(taken)
+}
+
+func (this *Perceptron) output(address uint32, history []bool) int32 {
+	weights := this.perceptron.weights[this.index(address)]
+	output := weights[0]
+	for i, bit := range history {
+		if bit {
+			output += weights[i+1]
+		} else {
+			output -= weights[i+1]
+		}
+	}
+	return output
+}
+
+// Saturating weight moved up when the history bit agrees with the outcome, down otherwise
+func (this *Perceptron) train(weight int32, agrees bool) int32 {
+	if agrees && weight < this.perceptron.maxWeight {
+		return weight + 1
+	}
+	if !agrees && weight > this.perceptron.minWeight {
+		return weight - 1
+	}
+	return weight
+}
+
+func (this *Perceptron) index(address uint32) uint32 {
+	return (address / this.perceptron.alignment) % uint32(len(this.perceptron.weights))
+}
+
+func abs(value int32) int32 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
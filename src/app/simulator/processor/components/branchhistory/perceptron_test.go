@@ -0,0 +1,48 @@
+package branchhistory
+
+import (
+	"testing"
+
+	"app/simulator/processor/config"
+)
+
+func TestWeightSaturation(t *testing.T) {
+	// 3 bits per weight: -4 to 3, the output never reaches the threshold so every branch trains
+	perceptronConfig := &config.PerceptronConfig{Entries: 64, HistoryLength: 8, WeightBits: 3, Threshold: 100}
+	perceptron, err := NewPerceptron(perceptronConfig, 4)
+	if err != nil {
+		t.Fatalf("Perceptron failed: %s", err.Error())
+	}
+	for i := 0; i < 20; i++ {
+		perceptron.Update(0x40, true, 0x44, perceptron.Predict(0x40, nil))
+	}
+	if perceptron.Trainings() != 20 {
+		t.Errorf("Trainings expected %d - Got %d", 20, perceptron.Trainings())
+	}
+	for i, weight := range perceptron.perceptron.weights[perceptron.index(0x40)] {
+		if weight != 3 {
+			t.Errorf("Weight %d expected %d - Got %d", i, 3, weight)
+		}
+	}
+}
+
+func TestTrainingThreshold(t *testing.T) {
+	// Threshold: 1.93 * 8 + 14 = 29
+	perceptronConfig := &config.PerceptronConfig{Entries: 64, HistoryLength: 8, WeightBits: 8}
+	perceptron, err := NewPerceptron(perceptronConfig, 4)
+	if err != nil {
+		t.Fatalf("Perceptron failed: %s", err.Error())
+	}
+	for i := 0; i < 40; i++ {
+		perceptron.Update(0x40, true, 0x44, perceptron.Predict(0x40, nil))
+	}
+
+	// Correct predictions past the threshold are not trained
+	trainings := perceptron.Trainings()
+	for i := 0; i < 10; i++ {
+		perceptron.Update(0x40, true, 0x44, perceptron.Predict(0x40, nil))
+	}
+	if perceptron.Trainings() != trainings {
+		t.Errorf("Trainings expected %d - Got %d", trainings, perceptron.Trainings())
+	}
+}
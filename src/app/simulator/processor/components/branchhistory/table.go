@@ -0,0 +1,88 @@
+package branchhistory
+
+import (
+	"errors"
+	"fmt"
+
+	"app/simulator/processor/config"
+)
+
+// Finite branch history table of the one_bit and two_bit predictors
+type Table struct {
+	*table
+}
+
+type table struct {
+	config    *config.BranchHistoryTableConfig
+	alignment uint32
+	entries   []tableEntry
+
+	// stats
+	aliasingMispredictions uint32
+}
+
+// The owner is the last branch that updated the entry, it is only kept to tell aliasing apart
+type tableEntry struct {
+	state uint32
+	tag   uint32
+	valid bool
+	owner uint32
+}
+
+func NewTable(tableConfig *config.BranchHistoryTableConfig, alignment uint32) (*Table, error) {
+	if tableConfig.IndexBits == 0 || tableConfig.IndexBits > 20 {
+		return nil, errors.New(fmt.Sprintf("Branch history table index bits must be between 1 and 20 (%d)", tableConfig.IndexBits))
+	}
+	if tableConfig.TagBits > 16 {
+		return nil, errors.New(fmt.Sprintf("Branch history table tag bits must be up to 16 (%d)", tableConfig.TagBits))
+	}
+	return &Table{
+		&table{
+			config:    tableConfig,
+			alignment: alignment,
+			entries:   make([]tableEntry, 1<<tableConfig.IndexBits),
+		},
+	}, nil
+}
+
+func (this *Table) Config() *config.BranchHistoryTableConfig {
+	return this.table.config
+}
+
+func (this *Table) AliasingMispredictions() uint32 {
+	return this.table.aliasingMispredictions
+}
+
+// Counters, tags and valid bits
+func (this *Table) StorageBits(predictorBits uint32) uint32 {
+	return uint32(len(this.table.entries)) * (predictorBits + this.Config().TagBits + 1)
+}
+
+// State of the entry of a branch, missing when it is empty or tagged by another branch
+func (this *Table) State(address uint32) (uint32, bool) {
+	index, tag := this.indexAndTag(address)
+	entry := this.table.entries[index]
+	if !entry.valid || entry.tag != tag {
+		return 0, false
+	}
+	return entry.state, true
+}
+
+func (this *Table) SetState(address uint32, state uint32) {
+	index, tag := this.indexAndTag(address)
+	this.table.entries[index] = tableEntry{state: state, tag: tag, valid: true, owner: address}
+}
+
+// A misprediction is caused by aliasing when the entry was last updated by another branch
+func (this *Table) LogMisprediction(address uint32) {
+	index, _ := this.indexAndTag(address)
+	entry := this.table.entries[index]
+	if entry.valid && entry.owner != address {
+		this.table.aliasingMispredictions += 1
+	}
+}
+
+func (this *Table) indexAndTag(address uint32) (uint32, uint32) {
+	pc := address / this.table.alignment
+	return pc & mask(this.Config().IndexBits), (pc >> this.Config().IndexBits) & mask(this.Config().TagBits)
+}
@@ -0,0 +1,252 @@
+package branchhistory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"app/logger"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/instruction"
+)
+
+func init() {
+	predictor.Register(config.TagePredictor, func(processorConfig *config.Config) (predictor.Predictor, error) {
+		tage, err := NewTage(processorConfig.Tage(), processorConfig.InstructionAlignment())
+		if err != nil {
+			return nil, err
+		}
+		return tage, nil
+	})
+}
+
+const (
+	TAGE_COUNTER_BITS = 3
+	TAGE_USEFUL_BITS  = 2
+	// Useful counters are halved every TAGE_RESET_PERIOD updates per tagged entry
+	TAGE_RESET_PERIOD = 256
+)
+
+// TAGE predictor: the longest tagged table matching the branch provides the prediction, the bimodal base otherwise
+type Tage struct {
+	*tage
+}
+
+type tage struct {
+	config         *config.TageConfig
+	alignment      uint32
+	indexBits      uint32
+	historyLengths []uint32
+	history        *GlobalHistory
+	base           []uint32
+	tables         [][]tageEntry
+	updates        uint32
+
+	// stats
+	providers   []uint32
+	allocations uint32
+}
+
+// Entries only match once allocated, an empty entry would match a branch with a zero tag
+type tageEntry struct {
+	valid   bool
+	counter uint32
+	tag     uint32
+	useful  uint32
+}
+
+// Tables matching a branch, -1 stands for the base table
+type tageLookup struct {
+	indexes   []uint32
+	tags      []uint32
+	provider  int
+	alternate int
+}
+
+func NewTage(tageConfig *config.TageConfig, alignment uint32) (*Tage, error) {
+	if tageConfig == nil {
+		return nil, errors.New("Branch predictor tage requires a tage object")
+	}
+	if _, ok := log2(tageConfig.BaseEntries); !ok {
+		return nil, errors.New(fmt.Sprintf("TAGE base entries must be a power of 2 (%d)", tageConfig.BaseEntries))
+	}
+	indexBits, ok := log2(tageConfig.TableEntries)
+	if !ok || tageConfig.Tables == 0 {
+		return nil, errors.New(fmt.Sprintf("TAGE needs at least one table with a power of 2 entries (%s)", tageConfig.ToString()))
+	}
+	if tageConfig.TagBits == 0 || tageConfig.TagBits > 16 {
+		return nil, errors.New(fmt.Sprintf("TAGE tag bits must be between 1 and 16 (%d)", tageConfig.TagBits))
+	}
+	if tageConfig.MinHistory == 0 || tageConfig.MinHistory > tageConfig.MaxHistory {
+		return nil, errors.New(fmt.Sprintf("TAGE history lengths must satisfy 0 < min <= max (%d, %d)", tageConfig.MinHistory, tageConfig.MaxHistory))
+	}
+
+	// Geometric series of history lengths from min to max
+	historyLengths := make([]uint32, tageConfig.Tables)
+	for i := range historyLengths {
+		ratio := float64(tageConfig.MaxHistory) / float64(tageConfig.MinHistory)
+		exponent := 0.0
+		if tageConfig.Tables > 1 {
+			exponent = float64(i) / float64(tageConfig.Tables-1)
+		}
+		historyLengths[i] = uint32(math.Floor(float64(tageConfig.MinHistory)*math.Pow(ratio, exponent) + 0.5))
+	}
+
+	tables := make([][]tageEntry, tageConfig.Tables)
+	for i := range tables {
+		tables[i] = make([]tageEntry, tageConfig.TableEntries)
+	}
+	return &Tage{
+		&tage{
+			config:         tageConfig,
+			alignment:      alignment,
+			indexBits:      indexBits,
+			historyLengths: historyLengths,
+			history:        NewGlobalHistory(tageConfig.MaxHistory),
+			base:           make([]uint32, tageConfig.BaseEntries),
+			tables:         tables,
+			providers:      make([]uint32, tageConfig.Tables+1),
+		},
+	}, nil
+}
+
+func (this *Tage) Config() *config.TageConfig {
+	return this.tage.config
+}
+
+func (this *Tage) HistoryLengths() []uint32 {
+	return this.tage.historyLengths
+}
+
+// Committed branches predicted by the base table (first) and by each tagged table
+func (this *Tage) Providers() []uint32 {
+	return this.tage.providers
+}
+
+func (this *Tage) Allocations() uint32 {
+	return this.tage.allocations
+}
+
+// Base counters, tagged entries (valid bit, counter, tag and useful bits) and the history register
+func (this *Tage) StorageBits() uint32 {
+	entryBits := 1 + TAGE_COUNTER_BITS + this.Config().TagBits + TAGE_USEFUL_BITS
+	return this.Config().BaseEntries*COUNTER_BITS + this.Config().Tables*this.Config().TableEntries*entryBits + this.Config().MaxHistory
+}
+
+func (this *Tage) Stats() string {
+	str := fmt.Sprintf(" => TAGE History Lengths: %v\n", this.HistoryLengths())
+	str += fmt.Sprintf(" => TAGE Base Predictions: %d\n", this.Providers()[0])
+	for i, provided := range this.Providers()[1:] {
+		str += fmt.Sprintf(" => TAGE Table %d Predictions: %d\n", i+1, provided)
+	}
+	str += fmt.Sprintf(" => TAGE Allocations: %d\n", this.Allocations())
+	str += fmt.Sprintf(" => Branch Predictor Storage: %d bits\n", this.StorageBits())
+	return str
+}
+
+func (this *Tage) Predict(pc uint32, instruction *instruction.Instruction) bool {
+	lookup := this.lookup(pc, this.tage.history.Bits(true))
+	taken := this.taken(pc, lookup, lookup.provider)
+	logger.Collect(" => [BP0]: Address %#04X, TAGE Provider: %d, Taken: %v", pc, lookup.provider+1, taken)
+	return taken
+}
+
+func (this *Tage) Speculate(pc uint32, taken bool) {
+	this.tage.history.Speculate(taken)
+}
+
+func (this *Tage) Restore() {
+	this.tage.history.Restore()
+}
+
+// Trained at commit with the committed history, the same one the branch was predicted with
+func (this *Tage) Update(address uint32, taken bool, target uint32, predicted bool) {
+	lookup := this.lookup(address, this.tage.history.Bits(false))
+	providerTaken := this.taken(address, lookup, lookup.provider)
+	alternateTaken := this.taken(address, lookup, lookup.alternate)
+	this.tage.providers[lookup.provider+1] += 1
+
+	// A misprediction allocates an entry on a longer table, or ages them when none is free
+	if providerTaken != taken && lookup.provider < len(this.tage.tables)-1 {
+		allocated := false
+		for i := lookup.provider + 1; i < len(this.tage.tables); i++ {
+			entry := &this.tage.tables[i][lookup.indexes[i]]
+			if entry.useful == 0 {
+				counter := uint32(1<<(TAGE_COUNTER_BITS-1)) - 1
+				if taken {
+					counter += 1
+				}
+				*entry = tageEntry{valid: true, counter: counter, tag: lookup.tags[i]}
+				this.tage.allocations += 1
+				allocated = true
+				break
+			}
+		}
+		if !allocated {
+			for i := lookup.provider + 1; i < len(this.tage.tables); i++ {
+				entry := &this.tage.tables[i][lookup.indexes[i]]
+				entry.useful = GetNextState(entry.useful, TAGE_USEFUL_BITS, false)
+			}
+		}
+	}
+
+	if lookup.provider < 0 {
+		index := this.baseIndex(address)
+		this.tage.base[index] = GetNextState(this.tage.base[index], COUNTER_BITS, taken)
+	} else {
+		entry := &this.tage.tables[lookup.provider][lookup.indexes[lookup.provider]]
+		entry.counter = GetNextState(entry.counter, TAGE_COUNTER_BITS, taken)
+		if providerTaken != alternateTaken {
+			entry.useful = GetNextState(entry.useful, TAGE_USEFUL_BITS, providerTaken == taken)
+		}
+	}
+
+	this.tage.updates += 1
+	if this.tage.updates%(TAGE_RESET_PERIOD*this.Config().TableEntries) == 0 {
+		for _, table := range this.tage.tables {
+			for i := range table {
+				table[i].useful >>= 1
+			}
+		}
+	}
+	this.tage.history.Commit(taken)
+}
+
+func (this *Tage) lookup(address uint32, history []bool) tageLookup {
+	pc := address / this.tage.alignment
+	tagBits := this.Config().TagBits
+	lookup := tageLookup{
+		indexes:   make([]uint32, len(this.tage.tables)),
+		tags:      make([]uint32, len(this.tage.tables)),
+		provider:  -1,
+		alternate: -1,
+	}
+	for i, length := range this.tage.historyLengths {
+		lookup.indexes[i] = (pc ^ pc>>this.tage.indexBits ^ fold(history, length, this.tage.indexBits)) & mask(this.tage.indexBits)
+		lookup.tags[i] = (pc ^ fold(history, length, tagBits) ^ fold(history, length, tagBits-1)<<1) & mask(tagBits)
+	}
+	for i := len(this.tage.tables) - 1; i >= 0; i-- {
+		entry := this.tage.tables[i][lookup.indexes[i]]
+		if entry.valid && entry.tag == lookup.tags[i] {
+			if lookup.provider < 0 {
+				lookup.provider = i
+			} else {
+				lookup.alternate = i
+				break
+			}
+		}
+	}
+	return lookup
+}
+
+func (this *Tage) taken(address uint32, lookup tageLookup, table int) bool {
+	if table < 0 {
+		return this.tage.base[this.baseIndex(address)] >= 1<<(COUNTER_BITS-1)
+	}
+	return this.tage.tables[table][lookup.indexes[table]].counter >= 1<<(TAGE_COUNTER_BITS-1)
+}
+
+func (this *Tage) baseIndex(address uint32) uint32 {
+	return (address / this.tage.alignment) % uint32(len(this.tage.base))
+}
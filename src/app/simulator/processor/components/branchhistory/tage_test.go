@@ -0,0 +1,51 @@
+package branchhistory
+
+import (
+	"testing"
+
+	"app/simulator/processor/config"
+)
+
+func TestHistoryLengths(t *testing.T) {
+	// Geometric series from 4 to 32: ratio 8 over 3 steps
+	tageConfig := &config.TageConfig{BaseEntries: 256, Tables: 4, TableEntries: 128, TagBits: 8, MinHistory: 4, MaxHistory: 32}
+	tage, err := NewTage(tageConfig, 4)
+	if err != nil {
+		t.Fatalf("TAGE failed: %s", err.Error())
+	}
+	for i, expected := range []uint32{4, 8, 16, 32} {
+		if tage.HistoryLengths()[i] != expected {
+			t.Errorf("Table %d history expected %d - Got %d", i+1, expected, tage.HistoryLengths()[i])
+		}
+	}
+}
+
+func TestAllocation(t *testing.T) {
+	tageConfig := &config.TageConfig{BaseEntries: 256, Tables: 4, TableEntries: 128, TagBits: 8, MinHistory: 4, MaxHistory: 32}
+	tage, err := NewTage(tageConfig, 4)
+	if err != nil {
+		t.Fatalf("TAGE failed: %s", err.Error())
+	}
+
+	// Base table predicts not taken, the misprediction allocates an entry on the first tagged table
+	tage.Update(0x40, true, 0x44, false)
+	if tage.Providers()[0] != 1 {
+		t.Errorf("Base predictions expected %d - Got %d", 1, tage.Providers()[0])
+	}
+	if tage.Allocations() != 1 {
+		t.Errorf("Allocations expected %d - Got %d", 1, tage.Allocations())
+	}
+}
+
+// Entries never allocated hold a zero tag, the one of address 0 with an empty history
+func TestUnallocatedEntries(t *testing.T) {
+	tageConfig := &config.TageConfig{BaseEntries: 256, Tables: 4, TableEntries: 128, TagBits: 8, MinHistory: 4, MaxHistory: 32}
+	tage, err := NewTage(tageConfig, 4)
+	if err != nil {
+		t.Fatalf("TAGE failed: %s", err.Error())
+	}
+	tage.Update(0, false, 4, false)
+	if tage.Providers()[0] != 1 {
+		t.Errorf("Base predictions expected %d - Got %d", 1, tage.Providers()[0])
+	}
+}
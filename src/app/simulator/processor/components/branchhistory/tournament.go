@@ -0,0 +1,189 @@
+package branchhistory
+
+import (
+	"errors"
+	"fmt"
+
+	"app/logger"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/instruction"
+)
+
+func init() {
+	predictor.Register(config.TournamentPredictor, func(processorConfig *config.Config) (predictor.Predictor, error) {
+		if processorConfig.BranchHistory() == nil {
+			return nil, errors.New(fmt.Sprintf("Branch predictor %s requires a branch_history object", config.TournamentPredictor))
+		}
+		tournament, err := NewTournament(processorConfig.BranchHistory(), processorConfig.InstructionAlignment())
+		if err != nil {
+			return nil, err
+		}
+		return tournament, nil
+	})
+}
+
+// Saturating counters of the chooser table, the upper half selects the global component
+const CHOOSER_BITS = 2
+
+// Tournament predictor: a local (PAg) and a global (gshare) component and a chooser between them
+type Tournament struct {
+	*tournament
+}
+
+type tournament struct {
+	config  *config.BranchHistoryConfig
+	local   *BranchHistory
+	global  *BranchHistory
+	chooser []uint32
+
+	// stats
+	localChosen   uint32
+	globalChosen  uint32
+	localCorrect  uint32
+	globalCorrect uint32
+}
+
+func NewTournament(historyConfig *config.BranchHistoryConfig, alignment uint32) (*Tournament, error) {
+	local, err := New(config.LocalPAgPredictor, historyConfig, alignment)
+	if err != nil {
+		return nil, err
+	}
+	global, err := New(config.GsharePredictor, historyConfig, alignment)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := log2(historyConfig.ChooserEntries); !ok {
+		return nil, errors.New(fmt.Sprintf("Chooser entries must be a power of 2 (%d)", historyConfig.ChooserEntries))
+	}
+	chooserIndex := historyConfig.ChooserIndexType()
+	if chooserIndex != config.AddressChooserIndex && chooserIndex != config.HistoryChooserIndex {
+		return nil, errors.New(fmt.Sprintf("Unknown chooser index %s", chooserIndex))
+	}
+	return &Tournament{
+		&tournament{
+			config:  historyConfig,
+			local:   local,
+			global:  global,
+			chooser: make([]uint32, historyConfig.ChooserEntries),
+		},
+	}, nil
+}
+
+func (this *Tournament) Config() *config.BranchHistoryConfig {
+	return this.tournament.config
+}
+
+func (this *Tournament) Local() *BranchHistory {
+	return this.tournament.local
+}
+
+func (this *Tournament) Global() *BranchHistory {
+	return this.tournament.global
+}
+
+func (this *Tournament) LocalChosen() uint32 {
+	return this.tournament.localChosen
+}
+
+func (this *Tournament) GlobalChosen() uint32 {
+	return this.tournament.globalChosen
+}
+
+func (this *Tournament) LocalCorrect() uint32 {
+	return this.tournament.localCorrect
+}
+
+func (this *Tournament) GlobalCorrect() uint32 {
+	return this.tournament.globalCorrect
+}
+
+// Both components and the chooser counters
+func (this *Tournament) StorageBits() uint32 {
+	return this.Local().StorageBits() + this.Global().StorageBits() + uint32(len(this.tournament.chooser))*CHOOSER_BITS
+}
+
+func (this *Tournament) Stats() string {
+	str := fmt.Sprintf(" => Local Predictor Chosen: %d\n", this.LocalChosen())
+	str += fmt.Sprintf(" => Global Predictor Chosen: %d\n", this.GlobalChosen())
+	str += fmt.Sprintf(" => Local Predictor Correct: %d\n", this.LocalCorrect())
+	str += fmt.Sprintf(" => Global Predictor Correct: %d\n", this.GlobalCorrect())
+	str += fmt.Sprintf(" => Branch Predictor Storage: %d bits\n", this.StorageBits())
+	return str
+}
+
+func (this *Tournament) Predict(pc uint32, instruction *instruction.Instruction) bool {
+	localTaken := this.Local().Predict(pc, instruction)
+	globalTaken := this.Global().Predict(pc, instruction)
+	state := this.Chooser(this.ChooserIndex(pc, true))
+	if this.ChoosesGlobal(state) {
+		logger.Collect(" => [BP0]: Address %#04X, Chooser State: %d, Global Taken: %v", pc, state, globalTaken)
+		return globalTaken
+	}
+	logger.Collect(" => [BP0]: Address %#04X, Chooser State: %d, Local Taken: %v", pc, state, localTaken)
+	return localTaken
+}
+
+// The chooser moves towards the component that was right, only when they disagree
+func (this *Tournament) Update(pc uint32, taken bool, target uint32, predicted bool) {
+	localTaken := this.Local().Taken(this.Local().State(this.Local().Index(pc, false)))
+	globalTaken := this.Global().Taken(this.Global().State(this.Global().Index(pc, false)))
+	chooserIndex := this.ChooserIndex(pc, false)
+	chooserState := this.Chooser(chooserIndex)
+
+	this.log(this.ChoosesGlobal(chooserState), localTaken == taken, globalTaken == taken)
+	if localTaken != globalTaken {
+		this.SetChooser(chooserIndex, GetNextState(chooserState, CHOOSER_BITS, globalTaken == taken))
+	}
+	this.Local().Update(pc, taken, target, predicted)
+	this.Global().Update(pc, taken, target, predicted)
+}
+
+// Chooser entry of a branch, with the speculative history at fetch or the committed one at commit
+func (this *Tournament) ChooserIndex(address uint32, speculative bool) uint32 {
+	entries := uint32(len(this.tournament.chooser))
+	if this.Config().ChooserIndexType() == config.HistoryChooserIndex {
+		this.Global().lock.Lock()
+		defer this.Global().lock.Unlock()
+		return this.Global().history(0, speculative) % entries
+	}
+	return (address / this.Global().alignment) % entries
+}
+
+func (this *Tournament) Chooser(index uint32) uint32 {
+	return this.tournament.chooser[index]
+}
+
+func (this *Tournament) SetChooser(index uint32, state uint32) {
+	this.tournament.chooser[index] = state
+}
+
+func (this *Tournament) ChoosesGlobal(state uint32) bool {
+	return state >= 1<<(CHOOSER_BITS-1)
+}
+
+// Counts the component chosen for a committed branch and which components were right
+func (this *Tournament) log(global bool, localCorrect bool, globalCorrect bool) {
+	if global {
+		this.tournament.globalChosen += 1
+	} else {
+		this.tournament.localChosen += 1
+	}
+	if localCorrect {
+		this.tournament.localCorrect += 1
+	}
+	if globalCorrect {
+		this.tournament.globalCorrect += 1
+	}
+}
+
+// Both components follow the predicted path
+func (this *Tournament) Speculate(address uint32, taken bool) {
+	this.Local().Speculate(address, taken)
+	this.Global().Speculate(address, taken)
+}
+
+func (this *Tournament) Restore() {
+	this.Local().Restore()
+	this.Global().Restore()
+}
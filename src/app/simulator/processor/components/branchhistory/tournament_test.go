@@ -0,0 +1,39 @@
+package branchhistory
+
+import (
+	"testing"
+
+	"app/simulator/processor/config"
+)
+
+func TestChooserIndex(t *testing.T) {
+	// Address: 0x4C / 4 = 19
+	// Bin: 10011, global history: 0110
+	indexes := []struct {
+		chooserIndex config.ChooserIndexType
+		index        uint32
+	}{
+		{config.AddressChooserIndex, 3}, // 10011 on 16 entries
+		{config.HistoryChooserIndex, 6}, // 0110
+	}
+	for _, expected := range indexes {
+		historyConfig := &config.BranchHistoryConfig{
+			HistoryLength:       4,
+			PatternTableEntries: 256,
+			LocalHistoryEntries: 16,
+			ChooserEntries:      16,
+			ChooserIndex:        expected.chooserIndex,
+		}
+		tournament, err := NewTournament(historyConfig, 4)
+		if err != nil {
+			t.Fatalf("Tournament failed: %s", err.Error())
+		}
+		for _, taken := range []bool{false, true, true, false} {
+			tournament.Global().Commit(0x4C, taken)
+		}
+		index := tournament.ChooserIndex(0x4C, false)
+		if index != expected.index {
+			t.Errorf("Chooser indexed by %s expected %d - Got %d", expected.chooserIndex, expected.index, index)
+		}
+	}
+}
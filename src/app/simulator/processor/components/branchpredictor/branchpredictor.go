@@ -2,16 +2,17 @@ package branchpredictor
 
 import (
 	"errors"
-	"math"
 	"time"
 
 	"app/logger"
 	"app/simulator/iprocessor"
 	"app/simulator/processor/components/pipeline/executor/branch"
+	"app/simulator/processor/components/predictor"
 	"app/simulator/processor/config"
-	"app/simulator/processor/consts"
 	"app/simulator/processor/models/info"
 	"app/simulator/processor/models/instruction"
+	"app/simulator/processor/models/operation"
+	"app/simulator/processor/models/set"
 )
 
 type BranchPredictor struct {
@@ -22,7 +23,6 @@ type branchPredictor struct {
 	predictorType config.PredictorType
 	index         uint32
 	processor     iprocessor.IProcessor
-	predictorBits uint32
 }
 
 func New(predictorType config.PredictorType, index uint32, processor iprocessor.IProcessor) *BranchPredictor {
@@ -33,30 +33,9 @@ func New(predictorType config.PredictorType, index uint32, processor iprocessor.
 			processor:     processor,
 		},
 	}
-	if predictorType == config.OneBitPredictor {
-		bp.predictorBits = 1
-	} else if predictorType == config.TwoBitPredictor {
-		bp.predictorBits = 2
-	}
-	bp.Processor().SetPredictorBits(bp.predictorBits)
 	return bp
 }
 
-func GetNextState(currentState uint32, predictorBits uint32, taken bool) uint32 {
-	maxValue := uint32(math.Exp2(float64(predictorBits))) - 1
-	if taken {
-		if currentState == maxValue {
-			return currentState
-		}
-		return currentState + 1
-	} else {
-		if currentState == 0 {
-			return currentState
-		}
-		return currentState - 1
-	}
-}
-
 func (this *BranchPredictor) PredictorType() config.PredictorType {
 	return this.branchPredictor.predictorType
 }
@@ -69,29 +48,25 @@ func (this *BranchPredictor) Processor() iprocessor.IProcessor {
 	return this.branchPredictor.processor
 }
 
-func (this *BranchPredictor) PredictorBits() uint32 {
-	return this.branchPredictor.predictorBits
-}
-
-func (this *BranchPredictor) PreDecodeInstruction(address uint32) (bool, *instruction.Instruction) {
+func (this *BranchPredictor) PreDecodeInstruction(op *operation.Operation) (bool, *instruction.Instruction) {
 
-	// Pre-decode to see if it is a branch instruction
-	data := this.Processor().InstructionsMemory().Load(address, consts.BYTES_PER_WORD)
-	instruction, _ := this.Processor().InstructionsSet().GetInstructionFromBytes(data)
+	// Pre-decode to see if it is a branch instruction (compressed instructions are already expanded)
+	instruction, _ := this.Processor().InstructionsSet().GetInstructionFromBytes(op.Word())
 
 	// Check if next instruction will need to wait because of a branch instruction
 	needsWait, _ := this.needsWait(instruction.Info)
 	return needsWait, instruction
 }
 
-func (this *BranchPredictor) GetNextAddress(address uint32, instruction *instruction.Instruction, forceStall bool) (uint32, bool, error) {
+func (this *BranchPredictor) GetNextAddress(op *operation.Operation, instruction *instruction.Instruction, forceStall bool) (uint32, bool, error) {
 
-	nextData := this.Processor().InstructionsMemory().Load(address, consts.BYTES_PER_WORD)
+	address := op.Address()
+	nextData := this.Processor().InstructionsMemory().Load(address, op.Size())
 	opId := this.Processor().InstructionsFetchedCounter() - 1
 	this.Processor().AddSpeculativeJump()
 
 	// Check if next instruction is valid
-	if this.Processor().ReachedEnd(nextData) {
+	if this.Processor().ReachedEnd(address, nextData) {
 		this.waitQueueInstructions()
 		this.processor.Finish()
 		logger.Collect(" => [BP%d][%03d]: Program reached the end", this.Index(), opId)
@@ -106,9 +81,20 @@ func (this *BranchPredictor) GetNextAddress(address uint32, instruction *instruc
 			this.Index(), this.Processor().InstructionsFetchedCounter()-1, this.Processor().InstructionsCompletedCounter(), opId)
 		this.waitQueueInstructions()
 		logger.Collect(" => [BP%d][%03d]: Waited for address resolution and got %#04X", this.Index(), opId, this.Processor().ProgramCounter())
+		if instruction.Info.IsConditionalBranch() {
+			this.speculateHistory(address, this.Processor().ProgramCounter() != op.NextAddress())
+		}
 		return this.Processor().ProgramCounter(), false, nil
 	} else {
-		newAddress := this.guessAddress(address, instruction)
+		newAddress := this.guessAddress(address, op.NextAddress(), instruction)
+		// End of a hardware loop body redirects the fetch back to its start
+		if !instruction.Info.IsBranch() {
+			loopAddress, looping := this.Processor().NextHardwareLoopAddress(op.NextAddress())
+			if looping {
+				logger.Collect(" => [BP%d][%03d]: Hardware loop back to address: %#04X", this.Index(), opId, loopAddress)
+				newAddress = loopAddress
+			}
+		}
 		if instruction.Info.IsBranch() {
 			logger.Collect(" => [BP%d][%03d]: Predicted address: %#04X", this.Index(), opId, newAddress)
 		}
@@ -136,55 +122,36 @@ func (this *BranchPredictor) isInstructionCompleted(operationId uint32) bool {
 }
 
 func (this *BranchPredictor) needsWait(info *info.Info) (bool, bool) {
+	// Hardware loop registers are set at commit, fetch resumes once they are known
+	if info.Opcode == set.OP_LOOP {
+		return true, false
+	}
 	needsWait := info.IsConditionalBranch() && this.PredictorType() == config.StallPredictor
 	speculativeExecution := info.IsConditionalBranch() && this.PredictorType() != config.StallPredictor
 	return needsWait, speculativeExecution
 }
 
-func (this *BranchPredictor) guessAddress(currentAddress uint32, instruction *instruction.Instruction) uint32 {
+func (this *BranchPredictor) guessAddress(currentAddress uint32, nextAddress uint32, instruction *instruction.Instruction) uint32 {
+	alignment := this.Processor().Config().InstructionAlignment()
 	if instruction.Info.IsUnconditionalBranch() {
 		// These are always taken
-		return branch.ComputeAddressTypeJ(instruction.Data)
+		return branch.ComputeAddressTypeJ(instruction.Data, alignment)
 	}
-	if instruction.Info.IsConditionalBranch() {
-		offset := branch.ComputeOffsetTypeI(instruction.Data)
-
-		switch this.PredictorType() {
-		case config.AlwaysTakenPredictor:
-			return uint32(int32(currentAddress) + offset + consts.BYTES_PER_WORD)
-		case config.NeverTakenPredictor:
-			return currentAddress + consts.BYTES_PER_WORD
-		case config.BackwardTakenPredictor:
-			if offset < 0 {
-				return uint32(int32(currentAddress) + offset + consts.BYTES_PER_WORD)
-			} else {
-				return currentAddress + consts.BYTES_PER_WORD
-			}
-		case config.ForwardTakenPredictor:
-			if offset > 0 {
-				return uint32(int32(currentAddress) + offset + consts.BYTES_PER_WORD)
-			} else {
-				return currentAddress + consts.BYTES_PER_WORD
-			}
-		case config.OneBitPredictor, config.TwoBitPredictor:
-			taken := this.getGuessByAddress(currentAddress)
-			if taken {
-				return uint32(int32(currentAddress) + offset + consts.BYTES_PER_WORD)
-			}
-			return currentAddress + consts.BYTES_PER_WORD
+	if instruction.Info.IsConditionalBranch() && this.Processor().Predictor() != nil {
+		offset := branch.ComputeOffsetTypeI(instruction.Data, alignment)
+		taken := this.Processor().Predictor().Predict(currentAddress, instruction)
+		this.speculateHistory(currentAddress, taken)
+		if taken {
+			return uint32(int32(nextAddress) + offset)
 		}
+		return nextAddress
 	}
-	return currentAddress + consts.BYTES_PER_WORD
+	return nextAddress
 }
 
-func (this *BranchPredictor) getGuessByAddress(address uint32) bool {
-	state, exists := this.Processor().GetBranchStateByAddress(address)
-	if !exists {
-		logger.Collect(" => [BP0]: No history for address %#04X", address)
-		return false
+// Younger branches are predicted with this outcome until the branch commits or a recovery repairs the history
+func (this *BranchPredictor) speculateHistory(address uint32, taken bool) {
+	if speculative, ok := this.Processor().Predictor().(predictor.Speculative); ok {
+		speculative.Speculate(address, taken)
 	}
-	totalStates := uint32(math.Exp2(float64(this.PredictorBits())))
-	taken := state >= totalStates/2
-	logger.Collect(" => [BP0]: Address %#04X, Total States: %d, State: %d ,Taken: %v", address, totalStates, state, taken)
-	return taken
 }
@@ -0,0 +1,167 @@
+package branchtargetbuffer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"app/simulator/processor/config"
+)
+
+type BranchTargetBuffer struct {
+	*branchTargetBuffer
+}
+
+type branchTargetBuffer struct {
+	config    *config.BranchTargetBufferConfig
+	alignment uint32
+	sets      [][]entry
+	accesses  uint32
+	pending   map[uint32]lookup
+	lock      sync.Mutex
+
+	// stats
+	lookups          uint32
+	committedLookups uint32
+	hits             uint32
+	missBubbles      uint32
+	wrongTargets     uint32
+}
+
+type entry struct {
+	address  uint32
+	target   uint32
+	valid    bool
+	lastUsed uint32
+}
+
+// Outcome of the lookup of a branch, counted once the branch commits
+type lookup struct {
+	hit         bool
+	wrongTarget bool
+	bubbles     uint32
+}
+
+func New(bufferConfig *config.BranchTargetBufferConfig, alignment uint32) (*BranchTargetBuffer, error) {
+	if bufferConfig.Entries == 0 || bufferConfig.Associativity == 0 || bufferConfig.Entries%bufferConfig.Associativity != 0 {
+		return nil, errors.New(fmt.Sprintf("Branch target buffer entries must be a multiple of its associativity (%s)", bufferConfig.ToString()))
+	}
+	sets := make([][]entry, bufferConfig.Entries/bufferConfig.Associativity)
+	for i := range sets {
+		sets[i] = make([]entry, bufferConfig.Associativity)
+	}
+	return &BranchTargetBuffer{
+		&branchTargetBuffer{
+			config:    bufferConfig,
+			alignment: alignment,
+			sets:      sets,
+			pending:   map[uint32]lookup{},
+		},
+	}, nil
+}
+
+func (this *BranchTargetBuffer) Config() *config.BranchTargetBufferConfig {
+	return this.branchTargetBuffer.config
+}
+
+// Lookups of committed branches
+func (this *BranchTargetBuffer) Lookups() uint32 {
+	return this.branchTargetBuffer.committedLookups
+}
+
+// Lookups of branches fetched on a wrong path, they update the entries but not the stats
+func (this *BranchTargetBuffer) WrongPathLookups() uint32 {
+	return this.branchTargetBuffer.lookups - this.branchTargetBuffer.committedLookups
+}
+
+func (this *BranchTargetBuffer) Hits() uint32 {
+	return this.branchTargetBuffer.hits
+}
+
+func (this *BranchTargetBuffer) HitRate() float32 {
+	if this.Lookups() == 0 {
+		return 0
+	}
+	return float32(this.Hits()) / float32(this.Lookups())
+}
+
+func (this *BranchTargetBuffer) MissBubbles() uint32 {
+	return this.branchTargetBuffer.missBubbles
+}
+
+// Hits on a stale target (e.g. a branch rewritten by self-modifying code)
+func (this *BranchTargetBuffer) WrongTargets() uint32 {
+	return this.branchTargetBuffer.wrongTargets
+}
+
+// Looks up a taken branch, on a miss the target is filled once decoded and the fetch waits the miss penalty
+func (this *BranchTargetBuffer) Lookup(operationId uint32, address uint32, target uint32) uint32 {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.branchTargetBuffer.accesses += 1
+	this.branchTargetBuffer.lookups += 1
+	set := this.branchTargetBuffer.sets[(address/this.branchTargetBuffer.alignment)%uint32(len(this.branchTargetBuffer.sets))]
+	for i := range set {
+		if set[i].valid && set[i].address == address {
+			set[i].lastUsed = this.branchTargetBuffer.accesses
+			if set[i].target == target {
+				this.branchTargetBuffer.pending[operationId] = lookup{hit: true}
+				return 0
+			}
+			set[i].target = target
+			return this.miss(operationId, true)
+		}
+	}
+
+	// Fill an empty way or the least recently used one
+	victim := 0
+	for i := range set {
+		if !set[i].valid {
+			victim = i
+			break
+		}
+		if set[i].lastUsed < set[victim].lastUsed {
+			victim = i
+		}
+	}
+	set[victim] = entry{address: address, target: target, valid: true, lastUsed: this.branchTargetBuffer.accesses}
+	return this.miss(operationId, false)
+}
+
+func (this *BranchTargetBuffer) miss(operationId uint32, wrongTarget bool) uint32 {
+	this.branchTargetBuffer.pending[operationId] = lookup{wrongTarget: wrongTarget, bubbles: this.Config().MissPenalty}
+	return this.Config().MissPenalty
+}
+
+// Counts the lookup of a committed branch, if it was looked up
+func (this *BranchTargetBuffer) Commit(operationId uint32) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	lookup, ok := this.branchTargetBuffer.pending[operationId]
+	if !ok {
+		return
+	}
+	delete(this.branchTargetBuffer.pending, operationId)
+	this.branchTargetBuffer.committedLookups += 1
+	if lookup.hit {
+		this.branchTargetBuffer.hits += 1
+	}
+	if lookup.wrongTarget {
+		this.branchTargetBuffer.wrongTargets += 1
+	}
+	this.branchTargetBuffer.missBubbles += lookup.bubbles
+}
+
+// Drops the lookups of the operations flushed after a misprediction
+func (this *BranchTargetBuffer) Flush(operationId uint32) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for id := range this.branchTargetBuffer.pending {
+		if id > operationId {
+			delete(this.branchTargetBuffer.pending, id)
+		}
+	}
+}
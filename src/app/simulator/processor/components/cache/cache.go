@@ -0,0 +1,272 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"app/simulator/processor/config"
+)
+
+type Cache struct {
+	*cache
+}
+
+type cache struct {
+	name   string
+	config *config.CacheConfig
+	next   Level
+	sets   [][]Line
+	ticks  uint32
+	random *rand.Rand
+	lock   sync.Mutex
+
+	// stats
+	reads        uint32
+	writes       uint32
+	hits         uint32
+	misses       uint32
+	evictions    uint32
+	writeBacks   uint32
+	memoryWrites uint32
+}
+
+type Line struct {
+	Valid    bool
+	Dirty    bool
+	Tag      uint32
+	LastUsed uint32
+	Inserted uint32
+}
+
+// Misses are served by the next level, or take the flat miss latency if there is none
+func New(name string, config *config.CacheConfig, next Level) (*Cache, error) {
+	if config.LineSize == 0 || config.Associativity == 0 || config.Size%(config.LineSize*config.Associativity) != 0 || config.Sets() == 0 {
+		return nil, errors.New(fmt.Sprintf("Cache %s size is not a non-zero multiple of its line size times its associativity (%s)", name, config.ToString()))
+	}
+	sets := make([][]Line, config.Sets())
+	for i := range sets {
+		sets[i] = make([]Line, config.Associativity)
+	}
+	return &Cache{
+		&cache{
+			name:   name,
+			config: config,
+			next:   next,
+			sets:   sets,
+			random: rand.New(rand.NewSource(1)),
+		},
+	}, nil
+}
+
+func (this *Cache) Name() string {
+	return this.cache.name
+}
+
+func (this *Cache) Config() *config.CacheConfig {
+	return this.cache.config
+}
+
+func (this *Cache) Next() Level {
+	return this.cache.next
+}
+
+func (this *Cache) Accesses() uint32 {
+	return this.cache.reads + this.cache.writes
+}
+
+func (this *Cache) Reads() uint32 {
+	return this.cache.reads
+}
+
+func (this *Cache) Writes() uint32 {
+	return this.cache.writes
+}
+
+func (this *Cache) Hits() uint32 {
+	return this.cache.hits
+}
+
+func (this *Cache) Misses() uint32 {
+	return this.cache.misses
+}
+
+func (this *Cache) Evictions() uint32 {
+	return this.cache.evictions
+}
+
+func (this *Cache) WriteBacks() uint32 {
+	return this.cache.writeBacks
+}
+
+func (this *Cache) MemoryWrites() uint32 {
+	return this.cache.memoryWrites
+}
+
+func (this *Cache) HitRate() float32 {
+	if this.Accesses() == 0 {
+		return 0
+	}
+	return float32(this.Hits()) / float32(this.Accesses())
+}
+
+// Returns the cycles taken by a read and whether it hit the cache
+func (this *Cache) Read(address uint32, size uint32) (uint32, bool) {
+	this.cache.lock.Lock()
+	defer this.cache.lock.Unlock()
+
+	this.cache.reads += 1
+	return this.access(address, size, false)
+}
+
+// Returns the cycles taken by a write and whether it hit the cache
+func (this *Cache) Write(address uint32, size uint32) (uint32, bool) {
+	this.cache.lock.Lock()
+	defer this.cache.lock.Unlock()
+
+	this.cache.writes += 1
+	if this.Config().WritePolicy == config.WriteThrough {
+		// Written through a write buffer, so it does not add to the latency
+		this.writeNext(address, size)
+	}
+	return this.access(address, size, true)
+}
+
+func (this *Cache) access(address uint32, size uint32, write bool) (uint32, bool) {
+	this.cache.ticks += 1
+	index, tag := this.getIndexAndTag(address)
+	set := this.cache.sets[index]
+	transfer := TransferCycles(size, this.Config().Bandwidth)
+
+	// Search line on the set
+	for i := range set {
+		if set[i].Valid && set[i].Tag == tag {
+			this.cache.hits += 1
+			set[i].LastUsed = this.cache.ticks
+			if write && this.Config().WritePolicy == config.WriteBack {
+				set[i].Dirty = true
+			}
+			return this.Config().HitLatency + transfer, true
+		}
+	}
+	this.cache.misses += 1
+
+	// Writes without allocation go straight to the next level
+	if write && !this.Config().WriteAllocate {
+		if this.Config().WritePolicy == config.WriteBack {
+			this.writeNext(address, size)
+		}
+		return this.Config().MissLatency, false
+	}
+
+	this.allocate(set, index, tag, write && this.Config().WritePolicy == config.WriteBack)
+	return this.getMissLatency(address) + transfer, false
+}
+
+// Brings a line ahead of any demand access, returns the cycles to fill it and false if it was already cached
+func (this *Cache) Prefetch(address uint32) (uint32, bool) {
+	this.cache.lock.Lock()
+	defer this.cache.lock.Unlock()
+
+	this.cache.ticks += 1
+	index, tag := this.getIndexAndTag(address)
+	set := this.cache.sets[index]
+	for i := range set {
+		if set[i].Valid && set[i].Tag == tag {
+			return 0, false
+		}
+	}
+	this.allocate(set, index, tag, false)
+	return this.getMissLatency(address), true
+}
+
+// Drops the lines holding the given bytes, dirty lines are written back first
+func (this *Cache) Invalidate(address uint32, size uint32) {
+	this.cache.lock.Lock()
+	defer this.cache.lock.Unlock()
+
+	lineSize := this.Config().LineSize
+	for line := address - address%lineSize; line < address+size; line += lineSize {
+		index, tag := this.getIndexAndTag(line)
+		set := this.cache.sets[index]
+		for i := range set {
+			if set[i].Valid && set[i].Tag == tag {
+				if set[i].Dirty {
+					this.cache.writeBacks += 1
+					this.writeNext(line, lineSize)
+				}
+				set[i] = Line{}
+			}
+		}
+	}
+}
+
+// Allocates the line replacing a victim
+func (this *Cache) allocate(set []Line, index uint32, tag uint32, dirty bool) {
+	victim := &set[this.getVictim(set)]
+	if victim.Valid {
+		this.cache.evictions += 1
+		if victim.Dirty {
+			this.cache.writeBacks += 1
+			this.writeNext(this.getLineAddress(index, victim.Tag), this.Config().LineSize)
+		}
+	}
+	*victim = Line{
+		Valid:    true,
+		Dirty:    dirty,
+		Tag:      tag,
+		LastUsed: this.cache.ticks,
+		Inserted: this.cache.ticks,
+	}
+}
+
+func (this *Cache) getMissLatency(address uint32) uint32 {
+	if this.Next() == nil {
+		return this.Config().MissLatency
+	}
+	// Look-up on this level plus the line fill from the next one
+	latency, _ := this.Next().Read(address-address%this.Config().LineSize, this.Config().LineSize)
+	return this.Config().HitLatency + latency
+}
+
+func (this *Cache) writeNext(address uint32, size uint32) {
+	this.cache.memoryWrites += 1
+	if this.Next() != nil {
+		this.Next().Write(address, size)
+	}
+}
+
+func (this *Cache) getIndexAndTag(address uint32) (uint32, uint32) {
+	block := address / this.Config().LineSize
+	return block % uint32(len(this.cache.sets)), block / uint32(len(this.cache.sets))
+}
+
+func (this *Cache) getLineAddress(index uint32, tag uint32) uint32 {
+	return (tag*uint32(len(this.cache.sets)) + index) * this.Config().LineSize
+}
+
+func (this *Cache) getVictim(set []Line) int {
+	victim := 0
+	for i := range set {
+		// Free lines are used first
+		if !set[i].Valid {
+			return i
+		}
+		switch this.Config().ReplacementPolicy {
+		case config.FIFOReplacement:
+			if set[i].Inserted < set[victim].Inserted {
+				victim = i
+			}
+		case config.RandomReplacement:
+		default:
+			if set[i].LastUsed < set[victim].LastUsed {
+				victim = i
+			}
+		}
+	}
+	if this.Config().ReplacementPolicy == config.RandomReplacement {
+		return this.cache.random.Intn(len(set))
+	}
+	return victim
+}
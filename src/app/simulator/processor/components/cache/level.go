@@ -0,0 +1,16 @@
+package cache
+
+// Level of the memory hierarchy, each access returns the cycles taken and whether it hit the level
+type Level interface {
+	Name() string
+	Read(address uint32, size uint32) (uint32, bool)
+	Write(address uint32, size uint32) (uint32, bool)
+}
+
+// Extra cycles needed to deliver size bytes to the upper level (first beat is part of the latency)
+func TransferCycles(size uint32, bandwidth uint32) uint32 {
+	if bandwidth == 0 || size <= bandwidth {
+		return 0
+	}
+	return (size+bandwidth-1)/bandwidth - 1
+}
@@ -0,0 +1,81 @@
+package device
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"app/simulator/processor/config"
+)
+
+// Value read once the input is exhausted
+const END_OF_INPUT = ^uint64(0)
+
+// UART-style console, a write prints a character and a read takes the next one from the input
+type Console struct {
+	*console
+}
+
+type console struct {
+	config *config.DeviceConfig
+	input  *bufio.Reader
+	output []byte
+
+	// stats
+	reads  uint32
+	writes uint32
+}
+
+func NewConsole(config *config.DeviceConfig) (*Console, error) {
+	var input io.Reader = os.Stdin
+	if config.InputFile != "" {
+		file, err := os.Open(config.InputFile)
+		if err != nil {
+			return nil, err
+		}
+		input = file
+	}
+	return &Console{
+		&console{
+			config: config,
+			input:  bufio.NewReader(input),
+			output: []byte{},
+		},
+	}, nil
+}
+
+func (this *Console) Name() string {
+	return this.console.config.Name
+}
+
+func (this *Console) Config() *config.DeviceConfig {
+	return this.console.config
+}
+
+func (this *Console) Reads() uint32 {
+	return this.console.reads
+}
+
+func (this *Console) Writes() uint32 {
+	return this.console.writes
+}
+
+func (this *Console) Output() string {
+	return string(this.console.output)
+}
+
+func (this *Console) Read(offset, size uint32) uint64 {
+	this.console.reads += 1
+	value, err := this.console.input.ReadByte()
+	if err != nil {
+		return END_OF_INPUT
+	}
+	return uint64(value)
+}
+
+func (this *Console) Write(offset, size uint32, value uint64) {
+	this.console.writes += 1
+	this.console.output = append(this.console.output, byte(value))
+	fmt.Printf("%c", byte(value))
+}
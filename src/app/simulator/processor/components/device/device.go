@@ -0,0 +1,95 @@
+package device
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"app/simulator/processor/config"
+	"app/simulator/processor/consts"
+)
+
+// Memory-mapped device, offsets are relative to the start of its address range
+type Device interface {
+	Name() string
+	Config() *config.DeviceConfig
+	Read(offset, size uint32) uint64
+	Write(offset, size uint32, value uint64)
+	Reads() uint32
+	Writes() uint32
+}
+
+type Bus struct {
+	*bus
+}
+
+type bus struct {
+	devices []Device
+	lock    sync.Mutex
+}
+
+func New(configs []*config.DeviceConfig, cycles func() uint32) (*Bus, error) {
+	devices := []Device{}
+	for _, cfg := range configs {
+		if cfg.Size == 0 {
+			return nil, errors.New(fmt.Sprintf("Device %s has an empty address range", cfg.Name))
+		}
+		for _, device := range devices {
+			other := device.Config()
+			if cfg.Start < other.Start+other.Size && other.Start < cfg.Start+cfg.Size {
+				return nil, errors.New(fmt.Sprintf("Device %s overlaps device %s", cfg.Name, other.Name))
+			}
+		}
+		var device Device
+		var err error
+		switch cfg.Type {
+		case config.ConsoleDevice:
+			device, err = NewConsole(cfg)
+		case config.TimerDevice:
+			device = NewTimer(cfg, cycles)
+		case config.RandomDevice:
+			device = NewRandom(cfg)
+		default:
+			err = errors.New(fmt.Sprintf("Unknown type %s for device %s", cfg.Type, cfg.Name))
+		}
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return &Bus{&bus{devices: devices}}, nil
+}
+
+func (this *Bus) Devices() []Device {
+	return this.bus.devices
+}
+
+// Returns the device whose address range overlaps the access, if any
+func (this *Bus) Find(address, size uint32) (Device, bool) {
+	for _, device := range this.Devices() {
+		cfg := device.Config()
+		if address < cfg.Start+cfg.Size && cfg.Start < address+size {
+			return device, true
+		}
+	}
+	return nil, false
+}
+
+func (this *Bus) Read(device Device, address, size uint32) uint64 {
+	this.bus.lock.Lock()
+	defer this.bus.lock.Unlock()
+	return device.Read(address-device.Config().Start, size) & sizeMask(size)
+}
+
+func (this *Bus) Write(device Device, address, size uint32, value uint64) {
+	this.bus.lock.Lock()
+	defer this.bus.lock.Unlock()
+	device.Write(address-device.Config().Start, size, value&sizeMask(size))
+}
+
+func sizeMask(size uint32) uint64 {
+	if size >= consts.BYTES_PER_DOUBLE {
+		return ^uint64(0)
+	}
+	return (uint64(1) << (size * consts.BITS_PER_BYTE)) - 1
+}
@@ -0,0 +1,57 @@
+package device
+
+import (
+	"math/rand"
+
+	"app/simulator/processor/config"
+)
+
+// Deterministic random number generator, every read returns the next number of the seeded sequence
+type Random struct {
+	*random
+}
+
+type random struct {
+	config    *config.DeviceConfig
+	generator *rand.Rand
+
+	// stats
+	reads  uint32
+	writes uint32
+}
+
+func NewRandom(config *config.DeviceConfig) *Random {
+	return &Random{
+		&random{
+			config:    config,
+			generator: rand.New(rand.NewSource(config.Seed)),
+		},
+	}
+}
+
+func (this *Random) Name() string {
+	return this.random.config.Name
+}
+
+func (this *Random) Config() *config.DeviceConfig {
+	return this.random.config
+}
+
+func (this *Random) Reads() uint32 {
+	return this.random.reads
+}
+
+func (this *Random) Writes() uint32 {
+	return this.random.writes
+}
+
+func (this *Random) Read(offset, size uint32) uint64 {
+	this.random.reads += 1
+	return this.random.generator.Uint64()
+}
+
+// Writing a value seeds the generator again
+func (this *Random) Write(offset, size uint32, value uint64) {
+	this.random.writes += 1
+	this.random.generator.Seed(int64(value))
+}
@@ -0,0 +1,55 @@
+package device
+
+import (
+	"app/simulator/processor/config"
+	"app/simulator/processor/consts"
+)
+
+// Free-running timer, reads return the cycles elapsed since the program started
+type Timer struct {
+	*timer
+}
+
+type timer struct {
+	config *config.DeviceConfig
+	cycles func() uint32
+
+	// stats
+	reads  uint32
+	writes uint32
+}
+
+func NewTimer(config *config.DeviceConfig, cycles func() uint32) *Timer {
+	return &Timer{
+		&timer{
+			config: config,
+			cycles: cycles,
+		},
+	}
+}
+
+func (this *Timer) Name() string {
+	return this.timer.config.Name
+}
+
+func (this *Timer) Config() *config.DeviceConfig {
+	return this.timer.config
+}
+
+func (this *Timer) Reads() uint32 {
+	return this.timer.reads
+}
+
+func (this *Timer) Writes() uint32 {
+	return this.timer.writes
+}
+
+func (this *Timer) Read(offset, size uint32) uint64 {
+	this.timer.reads += 1
+	return uint64(this.timer.cycles()) >> (offset * consts.BITS_PER_BYTE)
+}
+
+// Timer is read-only, writes are ignored
+func (this *Timer) Write(offset, size uint32, value uint64) {
+	this.timer.writes += 1
+}
@@ -0,0 +1,128 @@
+package dram
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"app/simulator/processor/components/cache"
+	"app/simulator/processor/config"
+)
+
+const CLOSED_ROW = -1
+
+type DRAM struct {
+	*dram
+}
+
+type dram struct {
+	name     string
+	config   *config.LevelConfig
+	openRows []int64
+	lock     sync.Mutex
+
+	// stats
+	reads        uint32
+	writes       uint32
+	rowHits      uint32
+	rowMisses    uint32
+	rowConflicts uint32
+}
+
+func New(name string, config *config.LevelConfig) (*DRAM, error) {
+	if config.Banks == 0 || config.RowSize == 0 {
+		return nil, errors.New(fmt.Sprintf("Memory level %s needs at least one DRAM bank and a non-zero row size (%s)", name, config.ToString()))
+	}
+	openRows := make([]int64, config.Banks)
+	for i := range openRows {
+		openRows[i] = CLOSED_ROW
+	}
+	return &DRAM{
+		&dram{
+			name:     name,
+			config:   config,
+			openRows: openRows,
+		},
+	}, nil
+}
+
+func (this *DRAM) Name() string {
+	return this.dram.name
+}
+
+func (this *DRAM) Config() *config.LevelConfig {
+	return this.dram.config
+}
+
+func (this *DRAM) Accesses() uint32 {
+	return this.dram.reads + this.dram.writes
+}
+
+func (this *DRAM) Reads() uint32 {
+	return this.dram.reads
+}
+
+func (this *DRAM) Writes() uint32 {
+	return this.dram.writes
+}
+
+func (this *DRAM) RowHits() uint32 {
+	return this.dram.rowHits
+}
+
+func (this *DRAM) RowMisses() uint32 {
+	return this.dram.rowMisses
+}
+
+func (this *DRAM) RowConflicts() uint32 {
+	return this.dram.rowConflicts
+}
+
+func (this *DRAM) RowHitRate() float32 {
+	if this.Accesses() == 0 {
+		return 0
+	}
+	return float32(this.RowHits()) / float32(this.Accesses())
+}
+
+// Returns the cycles taken by a read and whether it hit the open row
+func (this *DRAM) Read(address uint32, size uint32) (uint32, bool) {
+	this.dram.lock.Lock()
+	defer this.dram.lock.Unlock()
+
+	this.dram.reads += 1
+	return this.access(address, size)
+}
+
+// Returns the cycles taken by a write and whether it hit the open row
+func (this *DRAM) Write(address uint32, size uint32) (uint32, bool) {
+	this.dram.lock.Lock()
+	defer this.dram.lock.Unlock()
+
+	this.dram.writes += 1
+	return this.access(address, size)
+}
+
+func (this *DRAM) access(address uint32, size uint32) (uint32, bool) {
+	// Consecutive rows are interleaved across banks
+	row := address / this.Config().RowSize
+	bank := row % uint32(len(this.dram.openRows))
+	rowId := int64(row / uint32(len(this.dram.openRows)))
+
+	// Rows are left open after each access (open-page policy)
+	openRow := this.dram.openRows[bank]
+	this.dram.openRows[bank] = rowId
+
+	transfer := cache.TransferCycles(size, this.Config().Bandwidth)
+	switch openRow {
+	case rowId:
+		this.dram.rowHits += 1
+		return this.Config().RowHitLatency + transfer, true
+	case CLOSED_ROW:
+		this.dram.rowMisses += 1
+		return this.Config().RowMissLatency + transfer, false
+	default:
+		this.dram.rowConflicts += 1
+		return this.Config().RowConflictLatency + transfer, false
+	}
+}
@@ -0,0 +1,238 @@
+package loadstorequeue
+
+import (
+	"sync"
+
+	"app/logger"
+	"app/simulator/iprocessor"
+	"app/simulator/processor/components/memory"
+	"app/simulator/processor/config"
+	"app/simulator/processor/consts"
+	"app/simulator/processor/models/operation"
+	"app/simulator/processor/models/set"
+)
+
+const NO_SOURCE = -1
+
+type LoadStoreQueue struct {
+	*loadStoreQueue
+}
+
+type loadStoreQueue struct {
+	index             uint32
+	processor         iprocessor.IProcessor
+	loadQueueEntries  uint32
+	storeQueueEntries uint32
+	endianness        config.Endianness
+	loads             []*QueueEntry
+	stores            []*QueueEntry
+	lock              sync.Mutex
+}
+
+type QueueEntry struct {
+	Operation *operation.Operation
+	Address   uint32
+	Size      uint32
+	Value     uint64
+	Resolved  bool
+	// Store each byte of a load was forwarded from, NO_SOURCE when it was read from memory
+	Sources   []int64
+	Violation bool
+}
+
+func New(index uint32, processor iprocessor.IProcessor, loadQueueEntries, storeQueueEntries uint32, endianness config.Endianness) *LoadStoreQueue {
+	return &LoadStoreQueue{
+		&loadStoreQueue{
+			index:             index,
+			processor:         processor,
+			loadQueueEntries:  loadQueueEntries,
+			storeQueueEntries: storeQueueEntries,
+			endianness:        endianness,
+			loads:             []*QueueEntry{},
+			stores:            []*QueueEntry{},
+		},
+	}
+}
+
+func (this *LoadStoreQueue) Index() uint32 {
+	return this.loadStoreQueue.index
+}
+
+func (this *LoadStoreQueue) Processor() iprocessor.IProcessor {
+	return this.loadStoreQueue.processor
+}
+
+func (this *LoadStoreQueue) LoadQueueEntries() uint32 {
+	return this.loadStoreQueue.loadQueueEntries
+}
+
+func (this *LoadStoreQueue) StoreQueueEntries() uint32 {
+	return this.loadStoreQueue.storeQueueEntries
+}
+
+func (this *LoadStoreQueue) Endianness() config.Endianness {
+	return this.loadStoreQueue.endianness
+}
+
+// Plain loads & stores go through the queues, atomics are executed at the head of the ROB
+func IsQueued(opcode uint8) bool {
+	return set.AccessesMemory(opcode) && !set.IsAtomic(opcode)
+}
+
+// Returns false if the queue is full, entries are allocated in program order
+func (this *LoadStoreQueue) Allocate(op *operation.Operation) bool {
+	this.loadStoreQueue.lock.Lock()
+	defer this.loadStoreQueue.lock.Unlock()
+
+	entry := &QueueEntry{Operation: op}
+	if set.IsLoad(op.Instruction().Info.Opcode) {
+		if uint32(len(this.loadStoreQueue.loads)) >= this.LoadQueueEntries() {
+			return false
+		}
+		this.loadStoreQueue.loads = append(this.loadStoreQueue.loads, entry)
+	} else {
+		if uint32(len(this.loadStoreQueue.stores)) >= this.StoreQueueEntries() {
+			return false
+		}
+		this.loadStoreQueue.stores = append(this.loadStoreQueue.stores, entry)
+	}
+	return true
+}
+
+// Returns the bytes forwarded from older stores and the mask of the bytes covered by them
+func (this *LoadStoreQueue) Load(op *operation.Operation, address, size uint32) (uint64, uint64) {
+	this.loadStoreQueue.lock.Lock()
+	defer this.loadStoreQueue.lock.Unlock()
+
+	// Bytes are merged as laid out in memory, so they honour the endianness
+	endianness := this.Endianness()
+	valueBytes := make([]byte, size)
+	maskBytes := make([]byte, size)
+	sources := make([]int64, size)
+	for i := range sources {
+		sources[i] = NO_SOURCE
+	}
+	source := int64(NO_SOURCE)
+
+	// Older stores from the oldest to the youngest, so the youngest bytes prevail
+	for _, store := range this.loadStoreQueue.stores {
+		if store.Operation.Id() >= op.Id() {
+			break
+		}
+		// Stores with unknown address are speculatively ignored
+		if !store.Resolved || !overlaps(store.Address, store.Size, address, size) {
+			continue
+		}
+		storeBytes := memory.Encode(store.Value, store.Size, endianness)
+		for i := uint32(0); i < size; i++ {
+			byteAddress := address + i
+			if byteAddress >= store.Address && byteAddress < store.Address+store.Size {
+				valueBytes[i] = storeBytes[byteAddress-store.Address]
+				maskBytes[i] = 0xFF
+				sources[i] = int64(store.Operation.Id())
+			}
+		}
+		source = int64(store.Operation.Id())
+	}
+	value := memory.Decode(valueBytes, endianness)
+	mask := memory.Decode(maskBytes, endianness)
+
+	entry := this.getEntry(this.loadStoreQueue.loads, op.Id())
+	if entry != nil {
+		entry.Address = address
+		entry.Size = size
+		entry.Resolved = true
+		entry.Sources = sources
+	}
+
+	if mask != 0 {
+		partial := mask != sizeMask(size)
+		logger.Collect(" => [LQ%d][%03d]: Forwarded %#08X from store %d (partial: %v)", this.Index(), op.Id(), value, source, partial)
+		this.Processor().LogStoreForward(partial)
+	}
+	return value, mask
+}
+
+// Resolves the address and data of a store, and flags younger loads that already read stale data
+func (this *LoadStoreQueue) Store(op *operation.Operation, address, size uint32, value uint64) {
+	this.loadStoreQueue.lock.Lock()
+	defer this.loadStoreQueue.lock.Unlock()
+
+	entry := this.getEntry(this.loadStoreQueue.stores, op.Id())
+	if entry != nil {
+		entry.Address = address
+		entry.Size = size
+		entry.Value = value
+		entry.Resolved = true
+	}
+
+	for _, load := range this.loadStoreQueue.loads {
+		if load.Operation.Id() <= op.Id() || !load.Resolved || load.Violation {
+			continue
+		}
+		// Load got a byte of this store from memory or from a store older than this one
+		if readsStale(load, op.Id(), address, size) {
+			logger.Collect(" => [SQ%d][%03d]: Ordering violation, load %d already read %#X", this.Index(), op.Id(), load.Operation.Id(), load.Address)
+			load.Violation = true
+		}
+	}
+}
+
+// Violations are counted when the load is replayed, a flagged load on a wrong path is flushed with it
+
+func (this *LoadStoreQueue) HasViolation(op *operation.Operation) bool {
+	this.loadStoreQueue.lock.Lock()
+	defer this.loadStoreQueue.lock.Unlock()
+
+	entry := this.getEntry(this.loadStoreQueue.loads, op.Id())
+	return entry != nil && entry.Violation
+}
+
+// Entries are released once committed
+func (this *LoadStoreQueue) Release(op *operation.Operation) {
+	this.loadStoreQueue.lock.Lock()
+	defer this.loadStoreQueue.lock.Unlock()
+
+	this.loadStoreQueue.loads = removeEntry(this.loadStoreQueue.loads, op.Id())
+	this.loadStoreQueue.stores = removeEntry(this.loadStoreQueue.stores, op.Id())
+}
+
+func (this *LoadStoreQueue) getEntry(entries []*QueueEntry, operationId uint32) *QueueEntry {
+	for _, entry := range entries {
+		if entry.Operation.Id() == operationId {
+			return entry
+		}
+	}
+	return nil
+}
+
+func removeEntry(entries []*QueueEntry, operationId uint32) []*QueueEntry {
+	for i, entry := range entries {
+		if entry.Operation.Id() == operationId {
+			return append(entries[:i], entries[i+1:]...)
+		}
+	}
+	return entries
+}
+
+// Whether any byte the store writes was read by the load from memory or from an older store
+func readsStale(load *QueueEntry, storeId uint32, address, size uint32) bool {
+	for i, source := range load.Sources {
+		byteAddress := load.Address + uint32(i)
+		if byteAddress >= address && byteAddress < address+size && source < int64(storeId) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(addressA, sizeA, addressB, sizeB uint32) bool {
+	return addressA < addressB+sizeB && addressB < addressA+sizeA
+}
+
+func sizeMask(size uint32) uint64 {
+	if size >= consts.BYTES_PER_DOUBLE {
+		return ^uint64(0)
+	}
+	return (uint64(1) << (size * consts.BITS_PER_BYTE)) - 1
+}
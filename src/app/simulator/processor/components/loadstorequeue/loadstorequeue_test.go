@@ -0,0 +1,57 @@
+package loadstorequeue
+
+import (
+	"testing"
+
+	"app/simulator/iprocessor"
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/info"
+	"app/simulator/processor/models/instruction"
+	"app/simulator/processor/models/operation"
+	"app/simulator/processor/models/set"
+)
+
+// Only the forwards are logged by the queue
+type testProcessor struct {
+	iprocessor.IProcessor
+}
+
+func (this *testProcessor) LogStoreForward(partial bool) {}
+
+func newOperation(id uint32, opcode uint8) *operation.Operation {
+	op := operation.New(id, id*4)
+	op.SetInstruction(instruction.New(&info.Info{Opcode: opcode}, nil))
+	return op
+}
+
+func TestPartialOverlapViolation(t *testing.T) {
+	// Load of 4 bytes at 0x42 after a younger store S2 to 0x40 resolved:
+	// 0x42-0x43 are forwarded from S2, 0x44-0x45 are read from memory
+	// An older store S1 resolves afterwards, it is a violation when it writes a byte read from memory
+	stores := []struct {
+		address   uint32
+		size      uint32
+		violation bool
+	}{
+		{0x44, 4, true},
+		{0x42, 2, false}, // only bytes forwarded from S2, younger than S1
+		{0x46, 2, false},
+	}
+	for _, store := range stores {
+		lsq := New(0, &testProcessor{}, 4, 4, config.LittleEndian)
+		s1, s2, load := newOperation(1, set.OP_SW), newOperation(2, set.OP_SW), newOperation(3, set.OP_LW)
+		for _, op := range []*operation.Operation{s1, s2, load} {
+			lsq.Allocate(op)
+		}
+		lsq.Store(s2, 0x40, 4, 0x11223344)
+		value, mask := lsq.Load(load, 0x42, 4)
+		if value != 0x1122 || mask != 0xFFFF {
+			t.Errorf("Forwarded expected %#X (mask %#X) - Got %#X (mask %#X)", 0x1122, 0xFFFF, value, mask)
+		}
+
+		lsq.Store(s1, store.address, store.size, 0)
+		if lsq.HasViolation(load) != store.violation {
+			t.Errorf("Store of %d bytes at %#02X expected violation %v - Got %v", store.size, store.address, store.violation, !store.violation)
+		}
+	}
+}
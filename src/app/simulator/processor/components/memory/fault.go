@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"fmt"
+)
+
+type AccessType string
+
+const (
+	ReadAccess    AccessType = "read"
+	WriteAccess   AccessType = "write"
+	ExecuteAccess AccessType = "execute"
+)
+
+// Memory fault raised by an access, it is delivered once the operation commits
+type Fault struct {
+	// As wide as the datapath, so an address past 32 bits is reported whole
+	Address        uint64
+	Size           uint32
+	Access         AccessType
+	Reason         string
+	OperationId    uint32
+	ProgramCounter uint32
+}
+
+func (this *Fault) Error() string {
+	return fmt.Sprintf("Memory fault: %s of %d bytes at %#04X %s (PC: %#04X, OpId: %d)",
+		this.Access, this.Size, this.Address, this.Reason, this.ProgramCounter, this.OperationId)
+}
@@ -2,6 +2,10 @@ package memory
 
 import (
 	"fmt"
+	"strings"
+
+	"app/simulator/processor/config"
+	"app/simulator/processor/consts"
 )
 
 type Memory struct {
@@ -9,65 +13,187 @@ type Memory struct {
 }
 
 type memory struct {
-	size uint32
-	data []byte
+	size       uint32
+	wordSize   uint32
+	endianness config.Endianness
+	data       []byte
+	regions    []*config.MemoryRegion
 }
 
-func New(size uint32) *Memory {
+func New(size uint32, wordSize uint32) *Memory {
 	return &Memory{
 		&memory{
-			size: size,
-			data: make([]byte, size),
+			size:       size,
+			wordSize:   wordSize,
+			endianness: config.LittleEndian,
+			data:       make([]byte, size),
 		},
 	}
 }
 
+// Bytes of a value as they are laid out in memory
+func Encode(value uint64, size uint32, endianness config.Endianness) []byte {
+	bytes := make([]byte, size)
+	for i := uint32(0); i < size; i++ {
+		bytes[i] = byte(value >> byteShift(i, size, endianness))
+	}
+	return bytes
+}
+
+// Value of the bytes laid out in memory
+func Decode(bytes []byte, endianness config.Endianness) uint64 {
+	size := uint32(len(bytes))
+	value := uint64(0)
+	for i := uint32(0); i < size; i++ {
+		value |= uint64(bytes[i]) << byteShift(i, size, endianness)
+	}
+	return value
+}
+
+// Position in bits, within a value of size bytes, of its byte at the given offset
+func byteShift(offset, size uint32, endianness config.Endianness) uint32 {
+	if endianness == config.LittleEndian {
+		return offset * consts.BITS_PER_BYTE
+	}
+	return (size - 1 - offset) * consts.BITS_PER_BYTE
+}
+
 func (this *Memory) Size() uint32 {
 	return this.memory.size
 }
 
+func (this *Memory) WordSize() uint32 {
+	return this.memory.wordSize
+}
+
+func (this *Memory) Endianness() config.Endianness {
+	return this.memory.endianness
+}
+
+func (this *Memory) SetEndianness(endianness config.Endianness) {
+	this.memory.endianness = endianness
+}
+
 func (this *Memory) Data() []byte {
 	return this.memory.data
 }
 
+func (this *Memory) Regions() []*config.MemoryRegion {
+	return this.memory.regions
+}
+
+func (this *Memory) SetRegions(regions []*config.MemoryRegion) {
+	this.memory.regions = regions
+}
+
+func (this *Memory) InRange(address uint32, size uint32) bool {
+	return uint64(address)+uint64(size) <= uint64(this.Size())
+}
+
+// Returns a fault if the access goes out of the memory or it is not allowed by a protected region
+func (this *Memory) Check(address uint32, size uint32, access AccessType) error {
+	if !this.InRange(address, size) {
+		return &Fault{Address: uint64(address), Size: size, Access: access,
+			Reason: fmt.Sprintf("out of range [0x00, %#04X)", this.Size())}
+	}
+	for _, region := range this.Regions() {
+		if address >= region.Start+region.Size || region.Start >= address+size {
+			continue
+		}
+		if region.Access == config.NoAccessRegion || (region.Access == config.ReadOnlyRegion && access != ReadAccess) {
+			return &Fault{Address: uint64(address), Size: size, Access: access,
+				Reason: fmt.Sprintf("in %s region %s [%#04X, %#04X)", region.Access, region.Name, region.Start, region.Start+region.Size)}
+		}
+	}
+	return nil
+}
+
+// Bytes out of range are read as zeros and never written, accesses are checked before reaching here
 func (this *Memory) Load(address uint32, lenght uint32) []byte {
-	return this.memory.data[address : address+lenght]
+	if this.InRange(address, lenght) {
+		return this.memory.data[address : address+lenght]
+	}
+	bytes := make([]byte, lenght)
+	for i := range bytes {
+		bytes[i] = this.loadByte(address + uint32(i))
+	}
+	return bytes
 }
 
 func (this *Memory) LoadUint32(address uint32) uint32 {
-	return uint32(this.memory.data[address+3])<<24 +
-		uint32(this.memory.data[address+2])<<16 +
-		uint32(this.memory.data[address+1])<<8 +
-		uint32(this.memory.data[address+0])<<0
+	return uint32(this.LoadWord(address, consts.BYTES_PER_WORD))
+}
+
+func (this *Memory) LoadUint64(address uint32) uint64 {
+	return this.LoadWord(address, consts.BYTES_PER_DOUBLE)
+}
+
+// Loads a value of size bytes (byte, halfword, word or double) in the memory endianness
+func (this *Memory) LoadWord(address uint32, size uint32) uint64 {
+	return Decode(this.Load(address, size), this.Endianness())
 }
 
 func (this *Memory) Store(address uint32, values ...byte) {
 	for i, value := range values {
-		this.memory.data[address+uint32(i)] = value
+		this.storeByte(address+uint32(i), value)
 	}
 }
 
 func (this *Memory) StoreUint32(address uint32, value uint32) {
-	this.memory.data[address+3] = byte((value & 0xFF000000) >> 24)
-	this.memory.data[address+2] = byte((value & 0x00FF0000) >> 16)
-	this.memory.data[address+1] = byte((value & 0x0000FF00) >> 8)
-	this.memory.data[address+0] = byte((value & 0x000000FF) >> 0)
+	this.StoreWord(address, consts.BYTES_PER_WORD, uint64(value))
+}
+
+func (this *Memory) StoreUint64(address uint32, value uint64) {
+	this.StoreWord(address, consts.BYTES_PER_DOUBLE, value)
+}
+
+// Stores a value of size bytes (byte, halfword, word or double) in the memory endianness
+func (this *Memory) StoreWord(address uint32, size uint32, value uint64) {
+	this.Store(address, Encode(value, size, this.Endianness())...)
+}
+
+func (this *Memory) loadByte(address uint32) byte {
+	if address >= this.Size() {
+		return 0
+	}
+	return this.memory.data[address]
+}
+
+func (this *Memory) storeByte(address uint32, value byte) {
+	if address < this.Size() {
+		this.memory.data[address] = value
+	}
 }
 
 func (this *Memory) Clone() *Memory {
 	return &Memory{
 		&memory{
-			size: this.memory.size,
-			data: this.memory.data,
+			size:       this.memory.size,
+			wordSize:   this.memory.wordSize,
+			endianness: this.memory.endianness,
+			data:       this.memory.data,
+			regions:    this.memory.regions,
 		},
 	}
 }
 
 func (this *Memory) ToString() string {
-	str := "\t   0x00\t\t   0x04\t\t   0x08\t\t   0x0C\n"
+	// Four columns of 32 bits or two columns of 64 bits per row
+	wordSize := this.WordSize()
+	digits := wordSize * 2
+	columns := 16 / wordSize
+
+	str := "\t"
+	for c := uint32(0); c < columns; c++ {
+		str += fmt.Sprintf("   0x%02X%s", c*wordSize, strings.Repeat("\t", int(digits+2)/8+1))
+	}
+	str = strings.TrimRight(str, "\t") + "\n"
 	for i := uint32(0); i < this.Size(); i += 16 {
-		str += fmt.Sprintf("0x%02X\t0x%08X\t0x%08X\t0x%08X\t0x%08X\n",
-			i, this.LoadUint32(i), this.LoadUint32(i+4), this.LoadUint32(i+8), this.LoadUint32(i+12))
+		str += fmt.Sprintf("0x%02X", i)
+		for c := uint32(0); c < columns; c++ {
+			str += fmt.Sprintf("\t0x%0*X", digits, this.LoadWord(i+c*wordSize, wordSize))
+		}
+		str += "\n"
 	}
 	return str
 }
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"testing"
+
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/set"
+)
+
+var endiannesses = []config.Endianness{config.BigEndian, config.LittleEndian}
+var sizes = []uint32{1, 2, 4, 8}
+
+func TestEncodeLayout(t *testing.T) {
+	big := Encode(0x11223344, 4, config.BigEndian)
+	little := Encode(0x11223344, 4, config.LittleEndian)
+	for i, expected := range []byte{0x11, 0x22, 0x33, 0x44} {
+		if big[i] != expected {
+			t.Errorf("Big-endian byte %d expected %#02X - Got %#02X", i, expected, big[i])
+		}
+		if little[3-i] != expected {
+			t.Errorf("Little-endian byte %d expected %#02X - Got %#02X", 3-i, expected, little[3-i])
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	value := uint64(0x0123456789ABCDEF)
+	for _, endianness := range endiannesses {
+		for _, size := range sizes {
+			expected := value & (^uint64(0) >> (64 - size*8))
+			got := Decode(Encode(value, size, endianness), endianness)
+			if got != expected {
+				t.Errorf("%s-endian %d bytes expected %#X - Got %#X", endianness, size, expected, got)
+			}
+		}
+	}
+}
+
+func TestStoreLoadWord(t *testing.T) {
+	value := uint64(0xFEDCBA9876543210)
+	for _, endianness := range endiannesses {
+		for _, size := range sizes {
+			// Aligned and unaligned addresses, last one crossing the end of the memory
+			for _, address := range []uint32{0, 1, 3, 6, 28} {
+				memory := New(32, 4)
+				memory.SetEndianness(endianness)
+				memory.StoreWord(address, size, value)
+
+				expected := value & (^uint64(0) >> (64 - size*8))
+				if address+size > memory.Size() {
+					// Bytes out of range are never written and read as zeros
+					valid := Encode(value, size, endianness)[:memory.Size()-address]
+					expected = Decode(append(valid, make([]byte, size-uint32(len(valid)))...), endianness)
+				}
+				got := memory.LoadWord(address, size)
+				if got != expected {
+					t.Errorf("%s-endian %d bytes at %d expected %#X - Got %#X", endianness, size, address, expected, got)
+				}
+			}
+		}
+	}
+}
+
+func TestSubWordAccesses(t *testing.T) {
+	for _, endianness := range endiannesses {
+		memory := New(8, 4)
+		memory.SetEndianness(endianness)
+		memory.StoreUint32(0, 0x11223344)
+
+		// The lowest address holds the most significant byte only on big-endian
+		first, firstHalf := uint64(0x11), uint64(0x1122)
+		if endianness == config.LittleEndian {
+			first, firstHalf = 0x44, 0x3344
+		}
+		if got := memory.LoadWord(0, 1); got != first {
+			t.Errorf("%s-endian byte expected %#X - Got %#X", endianness, first, got)
+		}
+		if got := memory.LoadWord(0, 2); got != firstHalf {
+			t.Errorf("%s-endian halfword expected %#X - Got %#X", endianness, firstHalf, got)
+		}
+
+		// Byte and halfword stores keep the rest of the word
+		memory.StoreWord(0, 1, 0xAA)
+		memory.StoreWord(2, 2, 0xBBCC)
+		expected := uint32(0xAA22BBCC)
+		if endianness == config.LittleEndian {
+			expected = 0xBBCC33AA
+		}
+		if got := memory.LoadUint32(0); got != expected {
+			t.Errorf("%s-endian word expected %#X - Got %#X", endianness, expected, got)
+		}
+	}
+}
+
+func TestInstructionRoundTrip(t *testing.T) {
+	instructionSet := set.Init()
+	for _, endianness := range endiannesses {
+		for _, line := range []string{"LLI R1, 15", "ADD R3, R1, R2", "SW R9, R4, 24"} {
+			instruction, err := instructionSet.GetInstructionFromString(line, 4, 4, map[string]uint32{})
+			if err != nil {
+				t.Fatalf("Unexpected error parsing %s: %s", line, err.Error())
+			}
+
+			// Instructions are stored in the memory endianness and fetched in the canonical big-endian order
+			memory := New(8, 4)
+			memory.SetEndianness(endianness)
+			memory.StoreUint32(4, instruction.ToUint32())
+			word := Encode(Decode(memory.Load(4, 4), endianness), 4, config.BigEndian)
+
+			decoded, err := instructionSet.GetInstructionFromBytes(word)
+			if err != nil {
+				t.Fatalf("Unexpected error decoding %s: %s", line, err.Error())
+			}
+			if decoded.ToUint32() != instruction.ToUint32() {
+				t.Errorf("%s-endian %s expected %#08X - Got %#08X", endianness, line, instruction.ToUint32(), decoded.ToUint32())
+			}
+		}
+	}
+}
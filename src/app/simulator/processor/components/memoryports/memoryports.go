@@ -0,0 +1,160 @@
+package memoryports
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"app/simulator/processor/config"
+	"app/simulator/processor/consts"
+)
+
+type MemoryPorts struct {
+	*memoryPorts
+}
+
+type memoryPorts struct {
+	config *config.MemoryPortsConfig
+	cycles map[uint32]*cycleUsage
+	lock   sync.Mutex
+
+	// stats
+	reads              uint32
+	writes             uint32
+	readPortBusy       uint32
+	writePortBusy      uint32
+	bankConflictCycles uint32
+	bankAccesses       []uint32
+	// Last cycle counted by each counter, plus one so the first cycle is counted too
+	lastReadPortBusy  uint32
+	lastWritePortBusy uint32
+	lastBankConflict  uint32
+}
+
+// Ports and banks granted on a single cycle
+type cycleUsage struct {
+	reads  uint32
+	writes uint32
+	banks  map[uint32]bool
+}
+
+func New(config *config.MemoryPortsConfig) (*MemoryPorts, error) {
+	if config.ReadPorts == 0 || config.WritePorts == 0 || config.Banks == 0 {
+		return nil, errors.New(fmt.Sprintf("Memory ports need at least one read port, one write port and one bank (%s)", config.ToString()))
+	}
+	return &MemoryPorts{
+		&memoryPorts{
+			config:       config,
+			cycles:       map[uint32]*cycleUsage{},
+			bankAccesses: make([]uint32, config.Banks),
+		},
+	}, nil
+}
+
+func (this *MemoryPorts) Config() *config.MemoryPortsConfig {
+	return this.memoryPorts.config
+}
+
+func (this *MemoryPorts) Reads() uint32 {
+	return this.memoryPorts.reads
+}
+
+func (this *MemoryPorts) Writes() uint32 {
+	return this.memoryPorts.writes
+}
+
+func (this *MemoryPorts) ReadPortBusyCycles() uint32 {
+	return this.memoryPorts.readPortBusy
+}
+
+func (this *MemoryPorts) WritePortBusyCycles() uint32 {
+	return this.memoryPorts.writePortBusy
+}
+
+func (this *MemoryPorts) BankConflictCycles() uint32 {
+	return this.memoryPorts.bankConflictCycles
+}
+
+func (this *MemoryPorts) BankAccesses() []uint32 {
+	return this.memoryPorts.bankAccesses
+}
+
+// Word-interleaved banks touched by an access
+func (this *MemoryPorts) Banks(address, size uint32) []uint32 {
+	banks := []uint32{}
+	first := address / consts.BYTES_PER_WORD
+	last := (address + size - 1) / consts.BYTES_PER_WORD
+	for word := first; word <= last && word-first < this.Config().Banks; word++ {
+		banks = append(banks, word%this.Config().Banks)
+	}
+	return banks
+}
+
+// Grants a port and the banks of an access on the first free cycle, returns the cycles it is delayed
+func (this *MemoryPorts) Arbitrate(address, size uint32, write bool, cycle uint32) uint32 {
+	this.memoryPorts.lock.Lock()
+	defer this.memoryPorts.lock.Unlock()
+
+	// Cycles already elapsed can not be granted anymore
+	for c := range this.memoryPorts.cycles {
+		if c < cycle {
+			delete(this.memoryPorts.cycles, c)
+		}
+	}
+
+	banks := this.Banks(address, size)
+	for delay := uint32(0); ; delay++ {
+		usage := this.getUsage(cycle + delay)
+		if write && usage.writes >= this.Config().WritePorts {
+			countCycle(&this.memoryPorts.writePortBusy, &this.memoryPorts.lastWritePortBusy, cycle+delay)
+			continue
+		}
+		if !write && usage.reads >= this.Config().ReadPorts {
+			countCycle(&this.memoryPorts.readPortBusy, &this.memoryPorts.lastReadPortBusy, cycle+delay)
+			continue
+		}
+		if usage.conflicts(banks) {
+			countCycle(&this.memoryPorts.bankConflictCycles, &this.memoryPorts.lastBankConflict, cycle+delay)
+			continue
+		}
+
+		if write {
+			usage.writes += 1
+			this.memoryPorts.writes += 1
+		} else {
+			usage.reads += 1
+			this.memoryPorts.reads += 1
+		}
+		for _, bank := range banks {
+			usage.banks[bank] = true
+			this.memoryPorts.bankAccesses[bank] += 1
+		}
+		return delay
+	}
+}
+
+// Several accesses may wait on the same cycle, it is only counted once
+func countCycle(counter *uint32, last *uint32, cycle uint32) {
+	if cycle+1 > *last {
+		*counter += 1
+		*last = cycle + 1
+	}
+}
+
+func (this *MemoryPorts) getUsage(cycle uint32) *cycleUsage {
+	usage, ok := this.memoryPorts.cycles[cycle]
+	if !ok {
+		usage = &cycleUsage{banks: map[uint32]bool{}}
+		this.memoryPorts.cycles[cycle] = usage
+	}
+	return usage
+}
+
+func (this *cycleUsage) conflicts(banks []uint32) bool {
+	for _, bank := range banks {
+		if this.banks[bank] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+package mmu
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"app/simulator/processor/components/cache"
+	"app/simulator/processor/components/memory"
+	"app/simulator/processor/config"
+	"app/simulator/processor/consts"
+)
+
+// Page table entries hold the physical page address, with the flags in the lowest bits
+const (
+	PTE_VALID   = 0x1
+	PTE_READ    = 0x2
+	PTE_WRITE   = 0x4
+	PTE_EXECUTE = 0x8
+	PTE_FLAGS   = 0xF
+)
+
+type MMU struct {
+	*mmu
+}
+
+type mmu struct {
+	config         *config.VirtualMemoryConfig
+	pageTableBase  uint32
+	dataMemory     *memory.Memory
+	dataLevel      cache.Level
+	instructionTLB *TLB
+	dataTLB        *TLB
+	walkedPages    map[uint32]bool
+	lock           sync.Mutex
+
+	// stats
+	pageWalks       uint32
+	pageWalkCycles  uint32
+	pageFaults      uint32
+	pageTableStores uint32
+}
+
+// Page tables are read by the walker through the data memory hierarchy
+func New(config *config.VirtualMemoryConfig, dataMemory *memory.Memory, dataLevel cache.Level) (*MMU, error) {
+	if config.PageSize <= PTE_FLAGS || config.PageSize&(config.PageSize-1) != 0 {
+		return nil, errors.New(fmt.Sprintf("Page size %d is not a power of two larger than %d bytes", config.PageSize, PTE_FLAGS))
+	}
+	m := &MMU{
+		&mmu{
+			config:        config,
+			pageTableBase: config.PageTableBase,
+			dataMemory:    dataMemory,
+			dataLevel:     dataLevel,
+			walkedPages:   map[uint32]bool{},
+		},
+	}
+	var err error
+	if config.InstructionTLB != nil && config.InstructionTLB.Entries > 0 {
+		m.mmu.instructionTLB, err = NewTLB("ITLB", config.InstructionTLB)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.DataTLB != nil && config.DataTLB.Entries > 0 {
+		m.mmu.dataTLB, err = NewTLB("DTLB", config.DataTLB)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (this *MMU) Config() *config.VirtualMemoryConfig {
+	return this.mmu.config
+}
+
+func (this *MMU) PageSize() uint32 {
+	return this.Config().PageSize
+}
+
+func (this *MMU) PageTableBase() uint32 {
+	return this.mmu.pageTableBase
+}
+
+func (this *MMU) InstructionTLB() *TLB {
+	return this.mmu.instructionTLB
+}
+
+func (this *MMU) DataTLB() *TLB {
+	return this.mmu.dataTLB
+}
+
+func (this *MMU) TLBs() []*TLB {
+	tlbs := []*TLB{}
+	if this.InstructionTLB() != nil {
+		tlbs = append(tlbs, this.InstructionTLB())
+	}
+	if this.DataTLB() != nil {
+		tlbs = append(tlbs, this.DataTLB())
+	}
+	return tlbs
+}
+
+func (this *MMU) PageWalks() uint32 {
+	return this.mmu.pageWalks
+}
+
+func (this *MMU) PageWalkCycles() uint32 {
+	return this.mmu.pageWalkCycles
+}
+
+func (this *MMU) PageFaults() uint32 {
+	return this.mmu.pageFaults
+}
+
+func (this *MMU) PageTableStores() uint32 {
+	return this.mmu.pageTableStores
+}
+
+// Returns the physical address of a fetch and the cycles taken by the translation
+func (this *MMU) TranslateInstruction(address, size uint32) (uint32, uint32, error) {
+	this.mmu.lock.Lock()
+	defer this.mmu.lock.Unlock()
+	return this.translate(this.InstructionTLB(), address, size, memory.ExecuteAccess)
+}
+
+// Returns the physical address of a data access and the cycles taken by the translation
+func (this *MMU) TranslateData(address, size uint32, access memory.AccessType) (uint32, uint32, error) {
+	this.mmu.lock.Lock()
+	defer this.mmu.lock.Unlock()
+	return this.translate(this.DataTLB(), address, size, access)
+}
+
+// Reads the translation straight from the page table, without any timing or stats
+func (this *MMU) Probe(address uint32) (uint32, bool) {
+	this.mmu.lock.Lock()
+	defer this.mmu.lock.Unlock()
+
+	pageTableEntry, err := this.readPageTableEntry(address / this.PageSize())
+	if err != nil || pageTableEntry&PTE_VALID == 0 {
+		return 0, false
+	}
+	return this.physicalAddress(pageTableEntry, address), true
+}
+
+// Whether a store overwrites the page table entry of a page already walked, its translation may be in use
+func (this *MMU) IsPageTableStore(address, size uint32) bool {
+	this.mmu.lock.Lock()
+	defer this.mmu.lock.Unlock()
+	return len(this.storedPages(address, size)) > 0
+}
+
+// Drops the translations overwritten by a store, so the next access walks the page table again
+func (this *MMU) StorePageTable(address, size uint32) {
+	this.mmu.lock.Lock()
+	defer this.mmu.lock.Unlock()
+
+	pages := this.storedPages(address, size)
+	if len(pages) == 0 {
+		return
+	}
+	this.mmu.pageTableStores += 1
+	for _, page := range pages {
+		delete(this.mmu.walkedPages, page)
+		for _, tlb := range this.TLBs() {
+			tlb.Invalidate(page)
+		}
+	}
+}
+
+func (this *MMU) storedPages(address, size uint32) []uint32 {
+	pages := []uint32{}
+	if address+size <= this.PageTableBase() {
+		return pages
+	}
+	if address < this.PageTableBase() {
+		size, address = size-(this.PageTableBase()-address), this.PageTableBase()
+	}
+	first := (address - this.PageTableBase()) / consts.BYTES_PER_WORD
+	last := (address + size - 1 - this.PageTableBase()) / consts.BYTES_PER_WORD
+	for page := first; page <= last; page++ {
+		if this.mmu.walkedPages[page] {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+func (this *MMU) translate(tlb *TLB, address, size uint32, access memory.AccessType) (uint32, uint32, error) {
+	physical, cycles, err := this.translatePage(tlb, address, size, access)
+	if err != nil || address%this.PageSize()+size <= this.PageSize() {
+		return physical, cycles, err
+	}
+	// Accesses across two pages are only allowed if both are contiguous in physical memory
+	lastAddress := address + size - 1
+	lastPhysical, lastCycles, err := this.translatePage(tlb, lastAddress, size, access)
+	cycles += lastCycles
+	if err == nil && lastPhysical-physical != size-1 {
+		err = this.fault(address, size, access, "crossing non-contiguous pages (page fault)")
+	}
+	return physical, cycles, err
+}
+
+func (this *MMU) translatePage(tlb *TLB, address, size uint32, access memory.AccessType) (uint32, uint32, error) {
+	page := address / this.PageSize()
+	cycles := uint32(0)
+
+	pageTableEntry, hit := uint32(0), false
+	if tlb != nil {
+		pageTableEntry, hit = tlb.Lookup(page)
+	}
+	if !hit {
+		var err error
+		pageTableEntry, cycles, err = this.walk(page)
+		if err != nil {
+			return 0, cycles, this.fault(address, size, access, err.Error())
+		}
+		if tlb != nil {
+			cycles += tlb.Config().MissPenalty
+			tlb.Insert(page, pageTableEntry)
+		}
+		this.mmu.pageWalkCycles += cycles
+	}
+
+	if pageTableEntry&PTE_VALID == 0 {
+		return 0, cycles, this.fault(address, size, access, fmt.Sprintf("in unmapped page %d (page fault)", page))
+	}
+	if pageTableEntry&accessFlag(access) == 0 {
+		return 0, cycles, this.fault(address, size, access, fmt.Sprintf("in page %d without %s permission (page fault)", page, access))
+	}
+	return this.physicalAddress(pageTableEntry, address), cycles, nil
+}
+
+// Hardware page table walk, a single word read from the data memory
+func (this *MMU) walk(page uint32) (uint32, uint32, error) {
+	this.mmu.pageWalks += 1
+	this.mmu.walkedPages[page] = true
+	cycles := uint32(0)
+	if this.mmu.dataLevel != nil {
+		cycles, _ = this.mmu.dataLevel.Read(this.PageTableBase()+page*consts.BYTES_PER_WORD, consts.BYTES_PER_WORD)
+	}
+	pageTableEntry, err := this.readPageTableEntry(page)
+	return pageTableEntry, cycles, err
+}
+
+func (this *MMU) readPageTableEntry(page uint32) (uint32, error) {
+	address := this.PageTableBase() + page*consts.BYTES_PER_WORD
+	if !this.mmu.dataMemory.InRange(address, consts.BYTES_PER_WORD) {
+		return 0, errors.New(fmt.Sprintf("with page table entry %#04X out of the data memory (page fault)", address))
+	}
+	return this.mmu.dataMemory.LoadUint32(address), nil
+}
+
+func (this *MMU) physicalAddress(pageTableEntry, address uint32) uint32 {
+	return pageTableEntry&^(this.PageSize()-1) + address%this.PageSize()
+}
+
+func (this *MMU) fault(address, size uint32, access memory.AccessType, reason string) error {
+	this.mmu.pageFaults += 1
+	return &memory.Fault{Address: uint64(address), Size: size, Access: access, Reason: reason}
+}
+
+func accessFlag(access memory.AccessType) uint32 {
+	switch access {
+	case memory.WriteAccess:
+		return PTE_WRITE
+	case memory.ExecuteAccess:
+		return PTE_EXECUTE
+	}
+	return PTE_READ
+}
@@ -0,0 +1,116 @@
+package mmu
+
+import (
+	"errors"
+	"fmt"
+
+	"app/simulator/processor/config"
+)
+
+// Translation lookaside buffer, caches page table entries by virtual page number
+type TLB struct {
+	*tlb
+}
+
+type tlb struct {
+	name   string
+	config *config.TLBConfig
+	sets   [][]TLBEntry
+	ticks  uint32
+
+	// stats
+	hits   uint32
+	misses uint32
+}
+
+type TLBEntry struct {
+	Valid          bool
+	Page           uint32
+	PageTableEntry uint32
+	LastUsed       uint32
+}
+
+func NewTLB(name string, config *config.TLBConfig) (*TLB, error) {
+	if config.Associativity == 0 || config.Entries%config.Associativity != 0 {
+		return nil, errors.New(fmt.Sprintf("%s needs a non-zero associativity that divides its entries (%s)", name, config.ToString()))
+	}
+	sets := make([][]TLBEntry, config.Sets())
+	for i := range sets {
+		sets[i] = make([]TLBEntry, config.Associativity)
+	}
+	return &TLB{
+		&tlb{
+			name:   name,
+			config: config,
+			sets:   sets,
+		},
+	}, nil
+}
+
+func (this *TLB) Name() string {
+	return this.tlb.name
+}
+
+func (this *TLB) Config() *config.TLBConfig {
+	return this.tlb.config
+}
+
+func (this *TLB) Accesses() uint32 {
+	return this.tlb.hits + this.tlb.misses
+}
+
+func (this *TLB) Hits() uint32 {
+	return this.tlb.hits
+}
+
+func (this *TLB) Misses() uint32 {
+	return this.tlb.misses
+}
+
+func (this *TLB) HitRate() float32 {
+	if this.Accesses() == 0 {
+		return 0
+	}
+	return float32(this.Hits()) / float32(this.Accesses())
+}
+
+// Returns the page table entry of a virtual page if it is cached
+func (this *TLB) Lookup(page uint32) (uint32, bool) {
+	this.tlb.ticks += 1
+	set := this.tlb.sets[page%this.Config().Sets()]
+	for i := range set {
+		if set[i].Valid && set[i].Page == page {
+			this.tlb.hits += 1
+			set[i].LastUsed = this.tlb.ticks
+			return set[i].PageTableEntry, true
+		}
+	}
+	this.tlb.misses += 1
+	return 0, false
+}
+
+// Caches a page table entry, replacing the least recently used one of the set
+func (this *TLB) Insert(page uint32, pageTableEntry uint32) {
+	set := this.tlb.sets[page%this.Config().Sets()]
+	victim := 0
+	for i := range set {
+		if !set[i].Valid {
+			victim = i
+			break
+		}
+		if set[i].LastUsed < set[victim].LastUsed {
+			victim = i
+		}
+	}
+	set[victim] = TLBEntry{Valid: true, Page: page, PageTableEntry: pageTableEntry, LastUsed: this.tlb.ticks}
+}
+
+// Drops the cached page table entry of a virtual page, if any
+func (this *TLB) Invalidate(page uint32) {
+	set := this.tlb.sets[page%this.Config().Sets()]
+	for i := range set {
+		if set[i].Valid && set[i].Page == page {
+			set[i].Valid = false
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"app/logger"
 	"app/simulator/iprocessor"
 	"app/simulator/processor/components/channel"
+	"app/simulator/processor/components/loadstorequeue"
 	"app/simulator/processor/components/registeraliastable"
 	"app/simulator/processor/components/reorderbuffer"
 	"app/simulator/processor/components/reservationstation"
@@ -103,13 +104,22 @@ func (this *Dispatcher) Run(input channel.Channel, output map[info.CategoryEnum]
 	// Create register alias table
 	rat := registeraliastable.New(this.Index(), this.RegisterAliasTableEntries())
 
+	// Create load/store queue (if enabled)
+	var lsq *loadstorequeue.LoadStoreQueue
+	if this.Processor().Config().LoadStoreQueue() {
+		lsq = loadstorequeue.New(this.Index(), this.Processor(),
+			this.Processor().Config().LoadQueueEntries(),
+			this.Processor().Config().StoreQueueEntries(),
+			this.Processor().Config().DataEndianness())
+	}
+
 	// Create re-order buffer
 	rob := reorderbuffer.New(this.Index(),
 		this.Processor(),
 		this.StartOperationId(),
 		this.ReorderBufferEntries(),
 		this.InstructionsWrittenPerCycle(),
-		rat)
+		rat, lsq)
 	commonDataBusROB := channel.New(commonDataBus.Capacity())
 
 	// Create reservation station
@@ -127,7 +137,7 @@ func (this *Dispatcher) Run(input channel.Channel, output map[info.CategoryEnum]
 	logger.Print(" => Initializing dispatcher unit %d", this.Index())
 
 	// Start dispatcher of operations to be executed into reservation station
-	go this.runDispatcherToReservationStation(input, rs, rat, rob)
+	go this.runDispatcherToReservationStation(input, rs, rat, rob, lsq)
 	// Start common bus multiplexer to send ack to reservation station and reorder buffer
 	go this.runCommonBusMultiplexer(commonDataBus, commonDataBusRS, commonDataBusROB)
 
@@ -138,7 +148,7 @@ func (this *Dispatcher) Run(input channel.Channel, output map[info.CategoryEnum]
 }
 
 func (this *Dispatcher) runDispatcherToReservationStation(input channel.Channel,
-	rs *reservationstation.ReservationStation, rat *registeraliastable.RegisterAliasTable, rob *reorderbuffer.ReorderBuffer) {
+	rs *reservationstation.ReservationStation, rat *registeraliastable.RegisterAliasTable, rob *reorderbuffer.ReorderBuffer, lsq *loadstorequeue.LoadStoreQueue) {
 
 	incomingQueue := map[uint32]*operation.Operation{}
 	currentOperationId := this.StartOperationId()
@@ -161,15 +171,31 @@ func (this *Dispatcher) runDispatcherToReservationStation(input channel.Channel,
 			// Allocate in ROB if there is spacde, otherwise stall
 			rob.Allocate(op)
 
+			// Allocate in load/store queue if there is space, otherwise stall
+			if lsq != nil && loadstorequeue.IsQueued(op.Instruction().Info.Opcode) {
+				for !lsq.Allocate(op) {
+					logger.Collect(" => [DI%d][%03d]: Load/store queue is full. Wait for a free entry...", this.Index(), op.Id())
+					this.Processor().LogLoadStoreQueueFullStall()
+					this.Processor().Wait(1)
+					if !this.IsActive() {
+						return
+					}
+				}
+			}
+
 			// Rename register in case of WAR & WAR hazards
 			if this.RegisterAliasTableEntries() > 0 {
 				_, destRegister := rs.GetDestinationDependency(op.Id(), op.Instruction())
 				if destRegister != -1 {
 					found, _ := rat.AddMap(uint32(destRegister), op.Id())
-					if !found {
-						// Need to stall for an available RAT entry
+					for !found {
+						// Need to stall for an available RAT entry (released when older operations commit)
 						logger.Collect(" => [DI%d][%03d]: No entry available in RAT. Wait for one...", this.Index(), op.Id())
-						break
+						this.Processor().Wait(1)
+						if !this.IsActive() {
+							return
+						}
+						found, _ = rat.AddMap(uint32(destRegister), op.Id())
 					}
 
 					// Rename to physical registers
@@ -208,7 +234,7 @@ func (this *Dispatcher) renameRegisters(operationId uint32, op *operation.Operat
 		data := op.Instruction().Data.(*data.DataI)
 		if !op.Instruction().Info.IsBranch() {
 			opcode := op.Instruction().Info.Opcode
-			if opcode != set.OP_SW && opcode != set.OP_SLI && opcode != set.OP_SUI {
+			if opcode != set.OP_SW && opcode != set.OP_SD && opcode != set.OP_SLI && opcode != set.OP_SUI {
 				reg, _ := rat.GetPhysicalRegister(operationId, data.RegisterD.ToUint32())
 				op.SetRenamedDestRegister(reg)
 			}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"app/logger"
+	"app/simulator/processor/components/pipeline/executor/branch"
 	"app/simulator/processor/components/storagebus"
 	"app/simulator/processor/consts"
 	"app/simulator/processor/models/data"
@@ -19,20 +20,26 @@ type Alu struct {
 }
 
 type alu struct {
-	Result uint32
-	Status uint32
+	Result           uint64
+	Status           uint32
+	ArchitectureSize uint32
+	Alignment        uint32
 }
 
-func New(bus *storagebus.StorageBus) *Alu {
+func New(bus *storagebus.StorageBus, architectureSize uint32, alignment uint32) *Alu {
 	return &Alu{
-		bus: bus, alu: &alu{}}
+		bus: bus, alu: &alu{ArchitectureSize: architectureSize, Alignment: alignment}}
 }
 
 func (this *Alu) Bus() *storagebus.StorageBus {
 	return this.bus
 }
 
-func (this *Alu) SetResult(result uint32) {
+func (this *Alu) SetResult(result uint64) {
+	// Results are truncated to the width of the datapath
+	if this.alu.ArchitectureSize < 64 {
+		result &= (uint64(1) << this.alu.ArchitectureSize) - 1
+	}
 	this.alu.Result = result
 }
 
@@ -46,7 +53,7 @@ func (this *Alu) SetStatusFlag(value bool, flag uint8) {
 	}
 }
 
-func (this *Alu) Result() uint32 {
+func (this *Alu) Result() uint64 {
 	return this.alu.Result
 }
 
@@ -60,6 +67,16 @@ func (this *Alu) Process(operation *operation.Operation) (*operation.Operation,
 	// Clean Status
 	this.CleanStatus()
 
+	// Hardware loop setup does not write any register
+	if instruction.Info.Opcode == set.OP_LOOP {
+		countRegister := instruction.Data.(*data.DataI).RegisterD.ToUint32()
+		count := this.Bus().LoadRegister(operation, countRegister)
+		end := uint32(int32(operation.NextAddress()) + branch.ComputeOffsetTypeI(instruction.Data, this.alu.Alignment))
+		this.Bus().SetupLoop(operation, uint32(count), end)
+		logger.Collect(" => [ALU][%03d]: [LOOP R%d(%#02X) = %d, END = %#04X]", operation.Id(), countRegister, countRegister*consts.BYTES_PER_WORD, count, end)
+		return operation, nil
+	}
+
 	outputAddress, err := this.compute(operation, instruction.Info, instruction.Data)
 	if err != nil {
 		return operation, err
@@ -69,7 +86,7 @@ func (this *Alu) Process(operation *operation.Operation) (*operation.Operation,
 	// Set status flags
 	this.SetStatusFlag(this.Result()%2 == 0, consts.FLAG_PARITY)
 	this.SetStatusFlag(this.Result() == 0, consts.FLAG_ZERO)
-	this.SetStatusFlag(getSign(this.Result()), consts.FLAG_SIGN)
+	this.SetStatusFlag(this.getSign(this.Result()), consts.FLAG_SIGN)
 
 	// Persist output data
 	this.Bus().StoreRegister(operation, outputAddress, this.Result())
@@ -107,30 +124,54 @@ func (this *Alu) compute(op *operation.Operation, info *info.Info, operands inte
 		value1 := this.Bus().LoadRegister(op, op1)
 		value2 := this.Bus().LoadRegister(op, op2)
 		this.SetResult(value1 + value2)
-		this.SetStatusFlag(getSign(value1) == getSign(value2) && getSign(value1) != getSign(this.Result()), consts.FLAG_OVERFLOW)
+		this.SetStatusFlag(this.getSign(value1) == this.getSign(value2) && this.getSign(value1) != this.getSign(this.Result()), consts.FLAG_OVERFLOW)
 	case set.OP_ADDI:
 		value1 := this.Bus().LoadRegister(op, op1)
-		this.SetResult(value1 + op2)
-		this.SetStatusFlag(getSign(value1) == getSign(op2) && getSign(value1) != getSign(this.Result()), consts.FLAG_OVERFLOW)
+		this.SetResult(value1 + uint64(op2))
+		this.SetStatusFlag(this.getSign(value1) == this.getSign(uint64(op2)) && this.getSign(value1) != this.getSign(this.Result()), consts.FLAG_OVERFLOW)
 	case set.OP_ADDU:
 		this.SetResult(this.Bus().LoadRegister(op, op1) + this.Bus().LoadRegister(op, op2))
 	case set.OP_ADDIU:
-		this.SetResult(this.Bus().LoadRegister(op, op1) + op2)
+		this.SetResult(this.Bus().LoadRegister(op, op1) + uint64(op2))
 	case set.OP_SUB:
 		value1 := this.Bus().LoadRegister(op, op1)
 		value2 := this.Bus().LoadRegister(op, op2)
 		this.SetResult(value1 - value2)
-		this.SetStatusFlag(getSign(value1) != getSign(value2) && getSign(value2) == getSign(this.Result()), consts.FLAG_OVERFLOW)
+		this.SetStatusFlag(this.getSign(value1) != this.getSign(value2) && this.getSign(value2) == this.getSign(this.Result()), consts.FLAG_OVERFLOW)
 	case set.OP_SUBI:
 		value1 := this.Bus().LoadRegister(op, op1)
-		this.SetResult(value1 - op2)
-		this.SetStatusFlag(getSign(value1) != getSign(op2) && getSign(op2) == getSign(this.Result()), consts.FLAG_OVERFLOW)
+		this.SetResult(value1 - uint64(op2))
+		this.SetStatusFlag(this.getSign(value1) != this.getSign(uint64(op2)) && this.getSign(uint64(op2)) == this.getSign(this.Result()), consts.FLAG_OVERFLOW)
 	case set.OP_SUBU:
 		this.SetResult(this.Bus().LoadRegister(op, op1) - this.Bus().LoadRegister(op, op2))
 	case set.OP_MUL:
 		value1 := this.Bus().LoadRegister(op, op1)
 		value2 := this.Bus().LoadRegister(op, op2)
 		this.SetResult(value1 * value2)
+	// Word (32-bit) arithmetic, sign-extended to the datapath width
+	case set.OP_ADDW:
+		value1 := this.Bus().LoadRegister(op, op1)
+		value2 := this.Bus().LoadRegister(op, op2)
+		this.SetResult(signExtendWord(uint32(value1) + uint32(value2)))
+	case set.OP_ADDIW:
+		value1 := this.Bus().LoadRegister(op, op1)
+		this.SetResult(signExtendWord(uint32(value1) + op2))
+	case set.OP_SUBW:
+		value1 := this.Bus().LoadRegister(op, op1)
+		value2 := this.Bus().LoadRegister(op, op2)
+		this.SetResult(signExtendWord(uint32(value1) - uint32(value2)))
+	case set.OP_MULW:
+		value1 := this.Bus().LoadRegister(op, op1)
+		value2 := this.Bus().LoadRegister(op, op2)
+		this.SetResult(signExtendWord(uint32(value1) * uint32(value2)))
+	case set.OP_SHLW:
+		value1 := this.Bus().LoadRegister(op, op1)
+		value2 := this.Bus().LoadRegister(op, op2)
+		this.SetResult(signExtendWord(uint32(value1) << (value2 & 0x1F)))
+	case set.OP_SHRW:
+		value1 := this.Bus().LoadRegister(op, op1)
+		value2 := this.Bus().LoadRegister(op, op2)
+		this.SetResult(signExtendWord(uint32(value1) >> (value2 & 0x1F)))
 	// Bitwise Shifts
 	case set.OP_SHL:
 		value1 := this.Bus().LoadRegister(op, op1)
@@ -162,20 +203,31 @@ func (this *Alu) compute(op *operation.Operation, info *info.Info, operands inte
 		this.SetResult(value1 & value2)
 	case set.OP_ANDI:
 		value1 := this.Bus().LoadRegister(op, op1)
-		this.SetResult(value1 & op2)
+		this.SetResult(value1 & uint64(op2))
 	case set.OP_OR:
 		value1 := this.Bus().LoadRegister(op, op1)
 		value2 := this.Bus().LoadRegister(op, op2)
 		this.SetResult(value1 | value2)
 	case set.OP_ORI:
 		value1 := this.Bus().LoadRegister(op, op1)
-		this.SetResult(value1 | op2)
+		this.SetResult(value1 | uint64(op2))
+	// Performance counters
+	case set.OP_RDCYCLE:
+		this.SetResult(uint64(this.Bus().ReadCounter(op, consts.PMC_CYCLES)))
+	case set.OP_RDINSTRET:
+		this.SetResult(uint64(this.Bus().ReadCounter(op, consts.PMC_INSTRUCTIONS)))
+	case set.OP_RDPMC:
+		this.SetResult(uint64(this.Bus().ReadCounter(op, op2)))
 	default:
 		return 0, errors.New(fmt.Sprintf("Invalid operation to process by Alu unit. Opcode: %d", info.Opcode))
 	}
 	return outputAddr, nil
 }
 
-func getSign(value uint32) bool {
-	return (value >> 31) == 1
+func (this *Alu) getSign(value uint64) bool {
+	return (value>>(this.alu.ArchitectureSize-1))&0x01 == 1
+}
+
+func signExtendWord(value uint32) uint64 {
+	return uint64(int64(int32(value)))
 }
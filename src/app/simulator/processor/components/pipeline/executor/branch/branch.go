@@ -13,28 +13,29 @@ import (
 )
 
 type Branch struct {
-	bus *storagebus.StorageBus
+	bus       *storagebus.StorageBus
+	alignment uint32
 }
 
-func New(bus *storagebus.StorageBus) *Branch {
-	return &Branch{bus: bus}
+func New(bus *storagebus.StorageBus, alignment uint32) *Branch {
+	return &Branch{bus: bus, alignment: alignment}
 }
 
 func (this *Branch) Bus() *storagebus.StorageBus {
 	return this.bus
 }
 
-func ComputeOffsetTypeI(operands data.Data) int32 {
+func ComputeOffsetTypeI(operands data.Data, alignment uint32) int32 {
 	immediate := operands.(*data.DataI).Immediate.ToUint32()
 	// Cast as signed int32 so it gets performed Two's complement (if negative)
 	offsetAddress := int32(immediate<<16) >> 16
-	// Transform offset from N instructions domain to N bytes domain (2 bytes per instruction)
-	return offsetAddress << 2
+	// Transform offset from N instructions domain to N bytes domain (alignment bytes per instruction)
+	return offsetAddress * int32(alignment)
 }
 
-func ComputeAddressTypeJ(operands data.Data) uint32 {
-	// Transform address from N instructions domain to N bytes domain (2 bytes per instruction)
-	return operands.(*data.DataJ).Address.ToUint32() << 2
+func ComputeAddressTypeJ(operands data.Data, alignment uint32) uint32 {
+	// Transform address from N instructions domain to N bytes domain (alignment bytes per instruction)
+	return operands.(*data.DataJ).Address.ToUint32() * alignment
 }
 
 func (this *Branch) Process(operation *operation.Operation) (*operation.Operation, error) {
@@ -58,7 +59,7 @@ func (this *Branch) Process(operation *operation.Operation) (*operation.Operatio
 		operation.SetBranchResult(taken)
 
 		if taken {
-			offsetAddress := ComputeOffsetTypeI(operands)
+			offsetAddress := ComputeOffsetTypeI(operands, this.alignment)
 			this.Bus().IncrementProgramCounter(operation, offsetAddress)
 			logger.Collect(" => [BR][%03d]: [PC(offset) = 0x%06X", operation.Id(), offsetAddress)
 		} else {
@@ -66,8 +67,8 @@ func (this *Branch) Process(operation *operation.Operation) (*operation.Operatio
 			this.Bus().IncrementProgramCounter(operation, 0)
 		}
 	case data.TypeJ:
-		address := ComputeAddressTypeJ(operands)
-		this.Bus().SetProgramCounter(operation, uint32(address-consts.BYTES_PER_WORD))
+		address := ComputeAddressTypeJ(operands, this.alignment)
+		this.Bus().SetProgramCounter(operation, uint32(address-operation.Size()))
 		logger.Collect(" => [BR][%03d]: [Address = %06X]", operation.Id(), address)
 	default:
 		return operation, errors.New(fmt.Sprintf("Invalid data type to process by Branch unit. Type: %d", info.Type))
@@ -75,8 +76,8 @@ func (this *Branch) Process(operation *operation.Operation) (*operation.Operatio
 	return operation, nil
 }
 
-func processOperation(registerD uint32, registerS uint32, opcode uint8) (bool, error) {
-	switch opcode {
+func processOperation(registerD uint64, registerS uint64, opcode uint8) (bool, error) {
+	switch set.GetBranchCondition(opcode) {
 	case set.OP_BEQ:
 		return registerD == registerS, nil
 	case set.OP_BNE:
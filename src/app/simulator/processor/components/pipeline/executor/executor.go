@@ -94,11 +94,11 @@ func (this *Executor) Run(input map[info.CategoryEnum]channel.Channel, commonDat
 func (this *Executor) getUnitFromCategory(category info.CategoryEnum) (IExecutor, string) {
 	switch category {
 	case info.Aritmetic:
-		return alu.New(this.Bus()), consts.ALU_EVENT
+		return alu.New(this.Bus(), this.Processor().Config().ArchitectureSize(), this.Processor().Config().InstructionAlignment()), consts.ALU_EVENT
 	case info.LoadStore:
-		return loadstore.New(this.Bus()), consts.LOAD_STORE_EVENT
+		return loadstore.New(this.Bus(), this.Processor().Config().ArchitectureSize()), consts.LOAD_STORE_EVENT
 	case info.Control:
-		return branch.New(this.Bus()), consts.BRANCH_EVENT
+		return branch.New(this.Bus(), this.Processor().Config().InstructionAlignment()), consts.BRANCH_EVENT
 	case info.FloatingPoint:
 		return fpu.New(this.Bus()), consts.FPU_EVENT
 	}
@@ -49,7 +49,7 @@ func (this *Fpu) Process(operation *operation.Operation) (*operation.Operation,
 	logger.Collect(" => [FPU][%03d]: [R%d(%#02X) = %#08X]", operation.Id(), outputAddress, outputAddress*consts.BYTES_PER_WORD, this.Result())
 
 	// Persist output data
-	this.Bus().StoreRegister(operation, outputAddress, this.Result())
+	this.Bus().StoreRegister(operation, outputAddress, uint64(this.Result()))
 	return operation, nil
 }
 
@@ -80,20 +80,20 @@ func (this *Fpu) compute(op *operation.Operation, info *info.Info, operands inte
 
 	switch info.Opcode {
 	case set.OP_FADD:
-		val1 := this.Bus().LoadRegister(op, op1)
-		val2 := this.Bus().LoadRegister(op, op2)
+		val1 := uint32(this.Bus().LoadRegister(op, op1))
+		val2 := uint32(this.Bus().LoadRegister(op, op2))
 		this.SetResult(ieee754.PackFloat754_32(ieee754.UnPackFloat754_32(val1) + ieee754.UnPackFloat754_32(val2)))
 	case set.OP_FSUB:
-		val1 := this.Bus().LoadRegister(op, op1)
-		val2 := this.Bus().LoadRegister(op, op2)
+		val1 := uint32(this.Bus().LoadRegister(op, op1))
+		val2 := uint32(this.Bus().LoadRegister(op, op2))
 		this.SetResult(ieee754.PackFloat754_32(ieee754.UnPackFloat754_32(val1) - ieee754.UnPackFloat754_32(val2)))
 	case set.OP_FMUL:
-		val1 := this.Bus().LoadRegister(op, op1)
-		val2 := this.Bus().LoadRegister(op, op2)
+		val1 := uint32(this.Bus().LoadRegister(op, op1))
+		val2 := uint32(this.Bus().LoadRegister(op, op2))
 		this.SetResult(ieee754.PackFloat754_32(ieee754.UnPackFloat754_32(val1) * ieee754.UnPackFloat754_32(val2)))
 	case set.OP_FDIV:
-		val1 := this.Bus().LoadRegister(op, op1)
-		val2 := this.Bus().LoadRegister(op, op2)
+		val1 := uint32(this.Bus().LoadRegister(op, op1))
+		val2 := uint32(this.Bus().LoadRegister(op, op2))
 		this.SetResult(ieee754.PackFloat754_32(ieee754.UnPackFloat754_32(val1) / ieee754.UnPackFloat754_32(val2)))
 	default:
 		return 0, errors.New(fmt.Sprintf("Invalid operation to process by FPU unit. Opcode: %d", info.Opcode))
@@ -13,19 +13,60 @@ import (
 )
 
 type LoadStore struct {
-	bus *storagebus.StorageBus
+	bus              *storagebus.StorageBus
+	architectureSize uint32
 }
 
-func New(bus *storagebus.StorageBus) *LoadStore {
-	return &LoadStore{bus: bus}
+func New(bus *storagebus.StorageBus, architectureSize uint32) *LoadStore {
+	return &LoadStore{bus: bus, architectureSize: architectureSize}
 }
 
 func (this *LoadStore) Bus() *storagebus.StorageBus {
 	return this.bus
 }
 
+func (this *LoadStore) WordSize() uint32 {
+	return this.architectureSize / consts.BITS_PER_BYTE
+}
+
+// Addresses are computed as wide as the datapath, the memory checks they fit in 32 bits
+func (this *LoadStore) address(base uint64, offset uint32) uint64 {
+	address := base + uint64(offset)
+	if this.architectureSize <= consts.ARCHITECTURE_SIZE {
+		return uint64(uint32(address))
+	}
+	return address
+}
+
+// Words loaded into a wider register are sign-extended
+func (this *LoadStore) extendWord(value uint64) uint64 {
+	if this.architectureSize > consts.ARCHITECTURE_SIZE {
+		return uint64(int64(int32(uint32(value))))
+	}
+	return value
+}
+
 func (this *LoadStore) Process(operation *operation.Operation) (*operation.Operation, error) {
 
+	instruction := operation.Instruction()
+	switch instruction.Info.Type {
+	case data.TypeI:
+		return this.processTypeI(operation)
+	case data.TypeR:
+		return this.processAtomicTypeR(operation)
+	case data.TypeJ:
+		if instruction.Info.Opcode == set.OP_FENCE {
+			// All older memory operations are already committed, nothing else to do
+			this.Bus().Complete(operation)
+			logger.Collect(" => [LS][%03d]: [FENCE]", operation.Id())
+			return operation, nil
+		}
+	}
+	return operation, errors.New(fmt.Sprintf("Invalid operation to process by Data unit. Opcode: %d", instruction.Info.Opcode))
+}
+
+func (this *LoadStore) processTypeI(operation *operation.Operation) (*operation.Operation, error) {
+
 	instruction := operation.Instruction()
 	rdAddress := instruction.Data.(*data.DataI).RegisterD.ToUint32()
 	rsAddress := instruction.Data.(*data.DataI).RegisterS.ToUint32()
@@ -33,31 +74,90 @@ func (this *LoadStore) Process(operation *operation.Operation) (*operation.Opera
 
 	switch instruction.Info.Opcode {
 	case set.OP_LW:
-		rsValue := this.Bus().LoadRegister(operation, rsAddress)
-		value := this.Bus().LoadData(operation, rsValue+immediate)
+		address := this.address(this.Bus().LoadRegister(operation, rsAddress), immediate)
+		value := this.extendWord(this.Bus().LoadData(operation, address, consts.BYTES_PER_WORD))
 		this.Bus().StoreRegister(operation, rdAddress, value)
-		logger.Collect(" => [LS][%03d]: [R%d(%#02X) = MEM(%#02X) = %#08X]", operation.Id(), rdAddress, rdAddress*consts.BYTES_PER_WORD, rsValue+immediate, value)
+		logger.Collect(" => [LS][%03d]: [R%d(%#02X) = MEM(%#02X) = %#08X]", operation.Id(), rdAddress, rdAddress*consts.BYTES_PER_WORD, address, value)
 	case set.OP_SW:
-		rdValue := this.Bus().LoadRegister(operation, rdAddress)
+		address := this.address(this.Bus().LoadRegister(operation, rdAddress), immediate)
+		rsValue := this.Bus().LoadRegister(operation, rsAddress)
+		this.Bus().StoreData(operation, address, consts.BYTES_PER_WORD, rsValue)
+		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#08X]", operation.Id(), address, rsValue)
+	case set.OP_LD:
+		address := this.address(this.Bus().LoadRegister(operation, rsAddress), immediate)
+		value := this.Bus().LoadData(operation, address, consts.BYTES_PER_DOUBLE)
+		this.Bus().StoreRegister(operation, rdAddress, value)
+		logger.Collect(" => [LS][%03d]: [R%d(%#02X) = MEM(%#02X) = %#016X]", operation.Id(), rdAddress, rdAddress*consts.BYTES_PER_WORD, address, value)
+	case set.OP_SD:
+		address := this.address(this.Bus().LoadRegister(operation, rdAddress), immediate)
 		rsValue := this.Bus().LoadRegister(operation, rsAddress)
-		this.Bus().StoreData(operation, rdValue+immediate, rsValue)
-		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#08X]", operation.Id(), rdValue+immediate, rsValue)
+		this.Bus().StoreData(operation, address, consts.BYTES_PER_DOUBLE, rsValue)
+		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#016X]", operation.Id(), address, rsValue)
 	case set.OP_LLI:
-		this.Bus().StoreRegister(operation, rdAddress, immediate)
+		this.Bus().StoreRegister(operation, rdAddress, uint64(immediate))
 		logger.Collect(" => [LS][%03d]: [R%d(%#02X) = %#08X]", operation.Id(), rdAddress, rdAddress*consts.BYTES_PER_WORD, immediate)
 	case set.OP_SLI:
-		rdValue := this.Bus().LoadRegister(operation, rdAddress)
-		this.Bus().StoreData(operation, rdValue, immediate)
-		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#08X]", operation.Id(), rdValue, immediate)
+		address := this.address(this.Bus().LoadRegister(operation, rdAddress), 0)
+		this.Bus().StoreData(operation, address, consts.BYTES_PER_WORD, uint64(immediate))
+		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#08X]", operation.Id(), address, immediate)
 	case set.OP_LUI:
-		this.Bus().StoreRegister(operation, rdAddress, immediate<<16)
+		this.Bus().StoreRegister(operation, rdAddress, uint64(immediate<<16))
 		logger.Collect(" => [LS][%03d]: [R%d(%#02X) = %#08X]", operation.Id(), rdAddress, rdAddress*consts.BYTES_PER_WORD, immediate<<16)
 	case set.OP_SUI:
+		address := this.address(this.Bus().LoadRegister(operation, rdAddress), 0)
+		this.Bus().StoreData(operation, address, consts.BYTES_PER_WORD, uint64(immediate<<16))
+		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#08X]", operation.Id(), address, immediate<<16)
+	case set.OP_LL:
+		address := this.address(this.Bus().LoadRegister(operation, rsAddress), immediate)
+		value := this.Bus().LoadLinked(operation, address, this.WordSize())
+		this.Bus().StoreRegister(operation, rdAddress, value)
+		logger.Collect(" => [LS][%03d]: [R%d(%#02X) = MEM(%#02X) = %#08X] (linked)", operation.Id(), rdAddress, rdAddress*consts.BYTES_PER_WORD, address, value)
+	case set.OP_SC:
+		rdValue := this.Bus().LoadRegister(operation, rdAddress)
+		address := this.address(this.Bus().LoadRegister(operation, rsAddress), immediate)
+		result := uint64(0)
+		if this.Bus().StoreConditional(operation, address, this.WordSize(), rdValue) {
+			result = 1
+		}
+		this.Bus().StoreRegister(operation, rdAddress, result)
+		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#08X, R%d(%#02X) = %#08X] (conditional)", operation.Id(), address, rdValue, rdAddress, rdAddress*consts.BYTES_PER_WORD, result)
+	default:
+		return operation, errors.New(fmt.Sprintf("Invalid operation to process by Data unit. Opcode: %d", instruction.Info.Opcode))
+	}
+	return operation, nil
+}
+
+func (this *LoadStore) processAtomicTypeR(operation *operation.Operation) (*operation.Operation, error) {
+
+	instruction := operation.Instruction()
+	rdAddress := instruction.Data.(*data.DataR).RegisterD.ToUint32()
+	rsAddress := instruction.Data.(*data.DataR).RegisterS.ToUint32()
+	rtAddress := instruction.Data.(*data.DataR).RegisterT.ToUint32()
+
+	// Atomics run at the head of the ROB, so the read-modify-write cannot be interleaved
+	address := this.address(this.Bus().LoadRegister(operation, rsAddress), 0)
+	rtValue := this.Bus().LoadRegister(operation, rtAddress)
+	value := this.Bus().LoadData(operation, address, this.WordSize())
+
+	switch instruction.Info.Opcode {
+	case set.OP_CAS:
 		rdValue := this.Bus().LoadRegister(operation, rdAddress)
-		this.Bus().StoreData(operation, rdValue, immediate<<16)
-		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#08X]", operation.Id(), rdValue, immediate<<16)
+		if value == rdValue {
+			this.Bus().StoreAtomic(operation, address, this.WordSize(), rtValue)
+		}
+		logger.Collect(" => [LS][%03d]: [MEM(%#02X) == %#08X ? MEM(%#02X) = %#08X]", operation.Id(), address, rdValue, address, rtValue)
+	case set.OP_AMOADD:
+		this.Bus().StoreAtomic(operation, address, this.WordSize(), value+rtValue)
+		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#08X]", operation.Id(), address, value+rtValue)
+	case set.OP_AMOSWAP:
+		this.Bus().StoreAtomic(operation, address, this.WordSize(), rtValue)
+		logger.Collect(" => [LS][%03d]: [MEM(%#02X) = %#08X]", operation.Id(), address, rtValue)
 	default:
 		return operation, errors.New(fmt.Sprintf("Invalid operation to process by Data unit. Opcode: %d", instruction.Info.Opcode))
 	}
+
+	// Old memory value is always returned into the destination register
+	this.Bus().StoreRegister(operation, rdAddress, value)
+	logger.Collect(" => [LS][%03d]: [R%d(%#02X) = MEM(%#02X) = %#08X]", operation.Id(), rdAddress, rdAddress*consts.BYTES_PER_WORD, address, value)
 	return operation, nil
 }
@@ -8,9 +8,13 @@ import (
 	"app/simulator/iprocessor"
 	"app/simulator/processor/components/branchpredictor"
 	"app/simulator/processor/components/channel"
+	"app/simulator/processor/components/memory"
 	"app/simulator/processor/config"
 	"app/simulator/processor/consts"
+	"app/simulator/processor/models/data"
+	"app/simulator/processor/models/instruction"
 	"app/simulator/processor/models/operation"
+	"app/simulator/processor/models/set"
 )
 
 type Fetcher struct {
@@ -77,8 +81,16 @@ func (this *Fetcher) Run(input, output channel.Channel) {
 			// Initial operation (address)
 			op := operation.Cast(value)
 
-			// Load instructions data from memory
-			data := this.Processor().InstructionsMemory().Load(op.Address(), consts.BYTES_PER_WORD*this.InstructionsFetchedPerCycle())
+			// Load instructions data from memory (flushed while stalled on a miss)
+			data, fault := this.loadFetchBlock(op)
+			if !this.IsActive() {
+				logger.Print(" => Flushing fetcher unit %d", this.Index())
+				return
+			}
+			if fault != nil {
+				this.sendFetchFault(op, fault, output)
+				continue
+			}
 
 			// Fetch instructions
 			startCycles := this.Processor().Cycles()
@@ -105,16 +117,28 @@ func (this *Fetcher) Run(input, output channel.Channel) {
 func (this *Fetcher) fetchInstructions(op *operation.Operation, bytes []byte, input channel.Channel) ([]*operation.Operation, error) {
 
 	initialAddress := op.Address()
-	totalInstructions := len(bytes) / consts.BYTES_PER_WORD
+	fetchedBytes := uint32(0)
 	ops := []*operation.Operation{}
+	defer func() {
+		this.Processor().LogFetchBlock(uint32(len(ops)), fetchedBytes)
+	}()
 
-	// Analyze each instruction loaded
-	for i := 0; i < totalInstructions; i += 1 {
+	// Analyze each instruction loaded (split by their length)
+	size := uint32(0)
+	for offset := uint32(0); offset < uint32(len(bytes)); offset += size {
 
-		data := bytes[i*consts.BYTES_PER_WORD : (i+1)*consts.BYTES_PER_WORD]
+		size = this.getInstructionSize(bytes[offset])
+		if offset+size > uint32(len(bytes)) {
+			// Instruction split across fetch blocks, fetch it again on next cycle (unless out of memory)
+			if this.Processor().MMU() != nil || initialAddress+offset+size <= this.Processor().InstructionsMemory().Size() {
+				input.Add(op)
+			}
+			return ops, nil
+		}
+		data := bytes[offset : offset+size]
 
 		// Check program reach end
-		if this.Processor().ReachedEnd(data) {
+		if this.Processor().ReachedEnd(op.Address(), data) {
 			this.processor.Finish()
 			return ops, nil
 		}
@@ -131,18 +155,31 @@ func (this *Fetcher) fetchInstructions(op *operation.Operation, bytes []byte, in
 		this.Processor().LogInstructionFetched(op.Address())
 
 		// Update data into operation and add to array for post-events
-		op.SetWord([]byte{data[0], data[1], data[2], data[3]})
+		if size == consts.BYTES_PER_HALFWORD {
+			// Compressed instructions are expanded into their full word equivalent
+			word, err := set.Expand(data, op.Address())
+			if err != nil {
+				return ops, err
+			}
+			op.SetWord(word)
+		} else {
+			// Instruction words are decoded big-endian, whatever the byte order of the memory
+			word := memory.Decode(data, this.Processor().Config().InstructionsEndianness())
+			op.SetWord(memory.Encode(word, consts.BYTES_PER_WORD, config.BigEndian))
+		}
+		op.SetSize(size)
+		fetchedBytes += size
 
 		// Add operation to be sent to decode channel
 		ops = append(ops, op)
 
 		// Do pre-decode
-		needsWait, instruction := this.BranchPredictor().PreDecodeInstruction(op.Address())
+		needsWait, instruction := this.BranchPredictor().PreDecodeInstruction(op)
 
 		// If is not pipelined than wait instruction to finish
 		if !this.Processor().Config().Pipelined() {
 			go func() {
-				address, _, err := this.BranchPredictor().GetNextAddress(op.Address(), instruction, true)
+				address, _, err := this.BranchPredictor().GetNextAddress(op, instruction, true)
 				newOp := operation.New(this.Processor().InstructionsFetchedCounter(), address)
 				if err == nil {
 					input.Add(newOp)
@@ -156,7 +193,7 @@ func (this *Fetcher) fetchInstructions(op *operation.Operation, bytes []byte, in
 			logger.Collect(" => [FE%d][%03d]: Wait detected, no fetching more instructions this cycle", this.Index(), this.Processor().InstructionsFetchedCounter()-1)
 			// Add next instruction in a go routine as it need to be stalled
 			go func() {
-				address, _, err := this.BranchPredictor().GetNextAddress(op.Address(), instruction, false)
+				address, _, err := this.BranchPredictor().GetNextAddress(op, instruction, false)
 				newOp := operation.New(this.Processor().InstructionsFetchedCounter(), address)
 				if err == nil {
 					input.Add(newOp)
@@ -164,16 +201,28 @@ func (this *Fetcher) fetchInstructions(op *operation.Operation, bytes []byte, in
 			}()
 			return ops, nil
 		} else {
-			address, predicted, err := this.BranchPredictor().GetNextAddress(op.Address(), instruction, false)
+			address, predicted, err := this.BranchPredictor().GetNextAddress(op, instruction, false)
 			// Set current operation added to be decoded the predicted address
 			if predicted {
 				ops[len(ops)-1].SetNextPredictedAddress(address)
 			}
 
+			// Taken branches missing on the branch target buffer redirect the fetch once their target is decoded
+			bubbles := this.targetBubbles(op, instruction, address)
+
 			// Create new operation object
+			nextOffset := offset + size
 			op = operation.New(this.Processor().InstructionsFetchedCounter(), address)
+			if err == nil && bubbles > 0 {
+				logger.Collect(" => [FE%d][%03d]: BTB miss, fetch redirected in %d cycles", this.Index(), op.Id(), bubbles)
+				go func(op *operation.Operation) {
+					this.Processor().Wait(bubbles)
+					input.Add(op)
+				}(op)
+				return ops, nil
+			}
 			// If is the last instruction from the package or the predicted address is outside of the address package
-			if err == nil && (i >= totalInstructions-1 || initialAddress+(uint32(i+1)*consts.BYTES_PER_WORD) != op.Address()) {
+			if err == nil && (nextOffset >= uint32(len(bytes)) || initialAddress+nextOffset != op.Address()) {
 				input.Add(op)
 				return ops, nil
 			}
@@ -181,3 +230,124 @@ func (this *Fetcher) fetchInstructions(op *operation.Operation, bytes []byte, in
 	}
 	return ops, nil
 }
+
+func (this *Fetcher) targetBubbles(op *operation.Operation, instruction *instruction.Instruction, address uint32) uint32 {
+	if this.Processor().BranchTargetBuffer() == nil || !instruction.Info.IsBranch() || address == op.NextAddress() {
+		return 0
+	}
+	return this.Processor().BranchTargetBuffer().Lookup(op.Id(), op.Address(), address)
+}
+
+// A faulting fetch is sent down the pipeline as a fence, so the fault is raised once every older operation commits
+func (this *Fetcher) sendFetchFault(op *operation.Operation, fault *memory.Fault, output channel.Channel) {
+	fault.OperationId = op.Id()
+	fault.ProgramCounter = op.Address()
+	logger.Collect(" => [FE%d][%03d]: %s", this.Index(), op.Id(), fault.Error())
+
+	fence, _ := data.GetDataFromParts(data.TypeJ, set.OP_FENCE, 0)
+	word := fence.ToUint32()
+	op.SetWord([]byte{byte(word >> 24), byte(word >> 16), byte(word >> 8), byte(word)})
+	op.SetSize(this.Processor().Config().InstructionAlignment())
+	op.SetFault(fault)
+
+	this.Processor().LogInstructionFetched(op.Address())
+	this.Processor().LogEvent(consts.FETCH_EVENT, this.Index(), op.Id(), this.Processor().Cycles())
+	output.Add(op)
+}
+
+func (this *Fetcher) loadFetchBlock(op *operation.Operation) ([]byte, *memory.Fault) {
+	address, fault := this.translateFetchAddress(op, op.Address())
+	if fault != nil {
+		return nil, fault
+	}
+	length := consts.BYTES_PER_WORD * this.InstructionsFetchedPerCycle()
+
+	// Nothing is fetched out of the instructions memory, a jump there faults once committed
+	memorySize := this.Processor().InstructionsMemory().Size()
+	if address >= memorySize {
+		logger.Collect(" => [FE%d][%03d]: Address %#04X out of the instructions memory, no fetching", this.Index(), op.Id(), address)
+		return []byte{}, nil
+	}
+	if address+length > memorySize {
+		length = memorySize - address
+	}
+
+	if this.Processor().MMU() != nil {
+		// Fetch can not go across a page, the next one may be anywhere in the instructions memory
+		pageSize := this.Processor().MMU().PageSize()
+		pageRemaining := pageSize - address%pageSize
+		if pageRemaining < length {
+			length = pageRemaining
+		}
+		// Unless the instruction itself is split across two pages
+		instructionSize := this.getInstructionSize(this.Processor().InstructionsMemory().Load(address, 1)[0])
+		if length < instructionSize {
+			nextAddress, fault := this.translateFetchAddress(op, op.Address()+length)
+			if fault != nil {
+				return nil, fault
+			}
+			if this.Processor().InstructionMemoryLevel() != nil {
+				this.accessInstructionMemoryLevel(op, address, length)
+				this.accessInstructionMemoryLevel(op, nextAddress, instructionSize-length)
+			}
+			bytes := this.Processor().InstructionsMemory().Load(address, length)
+			return append(bytes, this.Processor().InstructionsMemory().Load(nextAddress, instructionSize-length)...), nil
+		}
+	}
+
+	instructionCache := this.Processor().InstructionCache()
+	if instructionCache != nil {
+		// Fetch can not go across a cache line on the same cycle
+		lineSize := instructionCache.Config().LineSize
+		lineRemaining := lineSize - address%lineSize
+		if lineRemaining < length {
+			length = lineRemaining
+		}
+		// Unless the instruction itself is split across two lines
+		firstByte := this.Processor().InstructionsMemory().Load(address, 1)[0]
+		if length < this.getInstructionSize(firstByte) {
+			length = this.getInstructionSize(firstByte)
+			this.accessInstructionMemoryLevel(op, address+lineRemaining, length-lineRemaining)
+		}
+	}
+	if this.Processor().InstructionMemoryLevel() != nil {
+		this.accessInstructionMemoryLevel(op, address, length)
+	}
+	return this.Processor().InstructionsMemory().Load(address, length), nil
+}
+
+// Returns the physical address of a fetch, translation stalls the fetch
+func (this *Fetcher) translateFetchAddress(op *operation.Operation, address uint32) (uint32, *memory.Fault) {
+	if this.Processor().MMU() == nil {
+		return address, nil
+	}
+	alignment := this.Processor().Config().InstructionAlignment()
+	physical, cycles, err := this.Processor().MMU().TranslateInstruction(address, alignment)
+	logger.Collect(" => [FE%d][%03d]: Translating INS[%#04X] to INS[%#04X] (%d cycles)", this.Index(), op.Id(), address, physical, cycles)
+	this.Processor().Wait(cycles)
+	if err == nil {
+		err = this.Processor().InstructionsMemory().Check(physical, alignment, memory.ExecuteAccess)
+	}
+	if err != nil {
+		return 0, err.(*memory.Fault)
+	}
+	return physical, nil
+}
+
+func (this *Fetcher) accessInstructionMemoryLevel(op *operation.Operation, address uint32, size uint32) {
+	level := this.Processor().InstructionMemoryLevel()
+	latency, hit := level.Read(address, size)
+	// L1I hits are served within the fetch stage, anything else stalls the fetch
+	if !hit || this.Processor().InstructionCache() == nil {
+		logger.Collect(" => [FE%d][%03d]: %s INS[%#04X] hit: %v, stalling %d cycles...", this.Index(), op.Id(), level.Name(), address, hit, latency)
+		this.Processor().LogFetchStall(latency)
+		this.Processor().Wait(latency)
+	}
+}
+
+func (this *Fetcher) getInstructionSize(firstByte byte) uint32 {
+	if this.Processor().Config().CompressedInstructions() && set.IsCompressed(firstByte) {
+		return consts.BYTES_PER_HALFWORD
+	}
+	return consts.BYTES_PER_WORD
+}
@@ -0,0 +1,66 @@
+package predictor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/instruction"
+)
+
+// Direction predictor of the conditional branches, asked at fetch and trained at commit
+type Predictor interface {
+	// Whether the conditional branch at pc is predicted taken
+	Predict(pc uint32, instruction *instruction.Instruction) bool
+	// Outcome of a committed conditional branch, the address it resolved to and the direction predicted at fetch
+	Update(pc uint32, taken bool, target uint32, predicted bool)
+}
+
+// Predictors with a history follow the predicted path at fetch, the history is repaired on every recovery
+type Speculative interface {
+	Speculate(pc uint32, taken bool)
+	Restore()
+}
+
+// Predictors with their own lines on the stats (e.g. their storage budget)
+type Reporter interface {
+	Stats() string
+}
+
+// Builds a predictor out of the processor configuration
+type Factory func(config *config.Config) (Predictor, error)
+
+var registry = map[config.PredictorType]Factory{}
+
+// Predictors register themselves by name from the init function of their package
+func Register(name config.PredictorType, factory Factory) {
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("Branch predictor %s registered twice", name))
+	}
+	registry[name] = factory
+}
+
+func Names() []string {
+	names := []string{}
+	for name := range registry {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func New(processorConfig *config.Config) (Predictor, error) {
+	return NewByName(processorConfig.BranchPredictorType(), processorConfig)
+}
+
+// Predictor of the given name, with its parameters taken from the configuration
+func NewByName(name config.PredictorType, processorConfig *config.Config) (Predictor, error) {
+	factory, exists := registry[name]
+	if !exists {
+		return nil, errors.New(fmt.Sprintf("Unknown branch predictor %s (expecting %s or one of: %s)",
+			name, config.StallPredictor, strings.Join(Names(), ", ")))
+	}
+	return factory(processorConfig)
+}
@@ -0,0 +1,21 @@
+package prefetcher
+
+// Prefetches the lines following the one accessed
+type nextLine struct {
+	lineSize uint32
+	degree   uint32
+	distance uint32
+}
+
+func newNextLine(lineSize, degree, distance uint32) *nextLine {
+	return &nextLine{lineSize: lineSize, degree: degree, distance: distance}
+}
+
+func (this *nextLine) Train(pc uint32, address uint32) []uint32 {
+	line := address / this.lineSize
+	addresses := []uint32{}
+	for i := uint32(0); i < this.degree; i++ {
+		addresses = append(addresses, (line+this.distance+i)*this.lineSize)
+	}
+	return addresses
+}
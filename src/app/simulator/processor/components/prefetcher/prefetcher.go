@@ -0,0 +1,161 @@
+package prefetcher
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"app/simulator/processor/components/cache"
+	"app/simulator/processor/config"
+)
+
+// Prefetching policy, it is trained with every data access and returns the addresses to prefetch
+type Policy interface {
+	Train(pc uint32, address uint32) []uint32
+}
+
+type Prefetcher struct {
+	*prefetcher
+}
+
+type prefetcher struct {
+	config   *config.PrefetcherConfig
+	policy   Policy
+	cache    *cache.Cache
+	inFlight map[uint32]uint32
+	lock     sync.Mutex
+
+	// stats
+	issued       uint32
+	useful       uint32
+	late         uint32
+	lateCycles   uint32
+	demandMisses uint32
+}
+
+// Prefetched lines are brought into the given cache, in flight lines are tracked with the cycle they are filled
+func New(prefetcherConfig *config.PrefetcherConfig, cache *cache.Cache) (*Prefetcher, error) {
+	if prefetcherConfig.Degree == 0 || prefetcherConfig.Distance == 0 {
+		return nil, errors.New(fmt.Sprintf("Prefetcher needs a degree and a distance of at least 1 (%s)", prefetcherConfig.ToString()))
+	}
+	lineSize := cache.Config().LineSize
+	var policy Policy
+	switch prefetcherConfig.Type {
+	case config.NextLinePrefetcher:
+		policy = newNextLine(lineSize, prefetcherConfig.Degree, prefetcherConfig.Distance)
+	case config.StridePrefetcher, config.StreamPrefetcher:
+		if prefetcherConfig.TableEntries == 0 {
+			return nil, errors.New(fmt.Sprintf("Prefetcher %s requires table entries", prefetcherConfig.Type))
+		}
+		if prefetcherConfig.Type == config.StridePrefetcher {
+			policy = newStride(prefetcherConfig.Degree, prefetcherConfig.Distance, prefetcherConfig.TableEntries)
+		} else {
+			policy = newStream(lineSize, prefetcherConfig.Degree, prefetcherConfig.Distance, prefetcherConfig.TableEntries)
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown prefetcher type %s", prefetcherConfig.Type))
+	}
+	return &Prefetcher{
+		&prefetcher{
+			config:   prefetcherConfig,
+			policy:   policy,
+			cache:    cache,
+			inFlight: map[uint32]uint32{},
+		},
+	}, nil
+}
+
+func (this *Prefetcher) Config() *config.PrefetcherConfig {
+	return this.prefetcher.config
+}
+
+func (this *Prefetcher) Cache() *cache.Cache {
+	return this.prefetcher.cache
+}
+
+func (this *Prefetcher) Issued() uint32 {
+	return this.prefetcher.issued
+}
+
+func (this *Prefetcher) Useful() uint32 {
+	return this.prefetcher.useful
+}
+
+func (this *Prefetcher) Late() uint32 {
+	return this.prefetcher.late
+}
+
+func (this *Prefetcher) LateCycles() uint32 {
+	return this.prefetcher.lateCycles
+}
+
+// Prefetched lines used by a demand access over all the prefetched lines
+func (this *Prefetcher) Accuracy() float32 {
+	if this.Issued() == 0 {
+		return 0
+	}
+	return float32(this.Useful()) / float32(this.Issued())
+}
+
+// Misses removed by the prefetcher over the misses there would be without it
+func (this *Prefetcher) Coverage() float32 {
+	if this.Useful()+this.prefetcher.demandMisses == 0 {
+		return 0
+	}
+	return float32(this.Useful()) / float32(this.Useful()+this.prefetcher.demandMisses)
+}
+
+// Useful prefetches filled before the demand access
+func (this *Prefetcher) Timeliness() float32 {
+	if this.Useful() == 0 {
+		return 0
+	}
+	return float32(this.Useful()-this.Late()) / float32(this.Useful())
+}
+
+// Trains the policy with a data access and issues its prefetches
+func (this *Prefetcher) Train(pc uint32, address uint32, cycle uint32) []uint32 {
+	this.prefetcher.lock.Lock()
+	defer this.prefetcher.lock.Unlock()
+
+	issued := []uint32{}
+	lineSize := this.Cache().Config().LineSize
+	for _, target := range this.prefetcher.policy.Train(pc, address) {
+		line := target - target%lineSize
+		if _, ok := this.prefetcher.inFlight[line]; ok {
+			continue
+		}
+		latency, ok := this.Cache().Prefetch(line)
+		if ok {
+			this.prefetcher.issued += 1
+			this.prefetcher.inFlight[line] = cycle + latency
+			issued = append(issued, line)
+		}
+	}
+	return issued
+}
+
+// Accounts a demand access of the cache, returns the cycles left to fill the line if it was prefetched too late
+func (this *Prefetcher) Demand(address uint32, hit bool, cycle uint32) uint32 {
+	this.prefetcher.lock.Lock()
+	defer this.prefetcher.lock.Unlock()
+
+	line := address - address%this.Cache().Config().LineSize
+	ready, ok := this.prefetcher.inFlight[line]
+	delete(this.prefetcher.inFlight, line)
+	// A miss on a prefetched line means it was evicted before being used
+	if !hit {
+		this.prefetcher.demandMisses += 1
+		return 0
+	}
+	if !ok {
+		return 0
+	}
+	this.prefetcher.useful += 1
+	if cycle < ready {
+		this.prefetcher.late += 1
+		this.prefetcher.lateCycles += ready - cycle
+		return ready - cycle
+	}
+	return 0
+}
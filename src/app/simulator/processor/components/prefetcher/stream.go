@@ -0,0 +1,71 @@
+package prefetcher
+
+// Lines away from the last line of a stream that still belong to it
+const STREAM_WINDOW = 2
+
+// Prefetches ahead of streams of ascending or descending lines, regardless of the instructions accessing them
+type stream struct {
+	lineSize uint32
+	degree   uint32
+	distance uint32
+	streams  []streamEntry
+	ticks    uint32
+}
+
+type streamEntry struct {
+	valid     bool
+	lastLine  uint32
+	direction int32
+	confirmed bool
+	lastUsed  uint32
+}
+
+func newStream(lineSize, degree, distance, tableEntries uint32) *stream {
+	return &stream{lineSize: lineSize, degree: degree, distance: distance, streams: make([]streamEntry, tableEntries)}
+}
+
+func (this *stream) Train(pc uint32, address uint32) []uint32 {
+	this.ticks += 1
+	line := address / this.lineSize
+	addresses := []uint32{}
+
+	for i := range this.streams {
+		entry := &this.streams[i]
+		delta := int32(line - entry.lastLine)
+		if !entry.valid || delta < -STREAM_WINDOW || delta > STREAM_WINDOW {
+			continue
+		}
+		entry.lastUsed = this.ticks
+		if delta == 0 {
+			return addresses
+		}
+		direction := int32(1)
+		if delta < 0 {
+			direction = -1
+		}
+		// A stream is confirmed once two accesses move in the same direction
+		entry.confirmed = direction == entry.direction
+		entry.direction = direction
+		entry.lastLine = line
+		if entry.confirmed {
+			for j := uint32(0); j < this.degree; j++ {
+				addresses = append(addresses, uint32(int32(line)+direction*int32(this.distance+j))*this.lineSize)
+			}
+		}
+		return addresses
+	}
+
+	// New stream replacing the least recently used one
+	victim := 0
+	for i := range this.streams {
+		if !this.streams[i].valid {
+			victim = i
+			break
+		}
+		if this.streams[i].lastUsed < this.streams[victim].lastUsed {
+			victim = i
+		}
+	}
+	this.streams[victim] = streamEntry{valid: true, lastLine: line, lastUsed: this.ticks}
+	return addresses
+}
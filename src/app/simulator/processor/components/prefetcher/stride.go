@@ -0,0 +1,55 @@
+package prefetcher
+
+import (
+	"app/simulator/processor/consts"
+)
+
+// Times the same stride has to be seen before prefetching
+const CONFIRMED_STRIDE = 1
+
+// Prefetches ahead of the stride followed by each memory instruction (PC-indexed table)
+type stride struct {
+	degree   uint32
+	distance uint32
+	entries  []strideEntry
+}
+
+type strideEntry struct {
+	valid       bool
+	pc          uint32
+	lastAddress uint32
+	stride      int32
+	confidence  uint32
+}
+
+func newStride(degree, distance, tableEntries uint32) *stride {
+	return &stride{degree: degree, distance: distance, entries: make([]strideEntry, tableEntries)}
+}
+
+func (this *stride) Train(pc uint32, address uint32) []uint32 {
+	entry := &this.entries[(pc/consts.BYTES_PER_HALFWORD)%uint32(len(this.entries))]
+	if !entry.valid || entry.pc != pc {
+		*entry = strideEntry{valid: true, pc: pc, lastAddress: address}
+		return []uint32{}
+	}
+
+	delta := int32(address - entry.lastAddress)
+	if delta == entry.stride && delta != 0 {
+		if entry.confidence < CONFIRMED_STRIDE {
+			entry.confidence += 1
+		}
+	} else {
+		entry.stride = delta
+		entry.confidence = 0
+	}
+	entry.lastAddress = address
+
+	addresses := []uint32{}
+	if entry.confidence < CONFIRMED_STRIDE {
+		return addresses
+	}
+	for i := uint32(0); i < this.degree; i++ {
+		addresses = append(addresses, uint32(int32(address)+entry.stride*int32(this.distance+i)))
+	}
+	return addresses
+}
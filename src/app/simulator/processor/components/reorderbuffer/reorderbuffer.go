@@ -1,13 +1,24 @@
 package reorderbuffer
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
 	"app/logger"
 	"app/simulator/iprocessor"
 	"app/simulator/processor/components/channel"
+	"app/simulator/processor/components/device"
+	"app/simulator/processor/components/loadstorequeue"
+	"app/simulator/processor/components/memory"
 	"app/simulator/processor/components/registeraliastable"
 	"app/simulator/processor/components/storagebus"
 	"app/simulator/processor/consts"
+	"app/simulator/processor/models/branchtrace"
+	"app/simulator/processor/models/memorytrace"
 	"app/simulator/processor/models/operation"
+	"app/simulator/processor/models/set"
 )
 
 type RobType string
@@ -16,6 +27,8 @@ const (
 	MemoryType         RobType = "M"
 	RegisterType       RobType = "R"
 	ProgramCounterType RobType = "PC"
+	LoopType           RobType = "LOOP"
+	NilType            RobType = "NIL"
 
 	AbsoluteType = 0
 	OffsetType   = 1
@@ -30,31 +43,42 @@ type reorderBuffer struct {
 	processor                   iprocessor.IProcessor
 	bus                         *storagebus.StorageBus
 	startOperationId            uint32
+	headOperationId             uint32
 	buffer                      map[uint32]RobEntry
+	lock                        sync.RWMutex
 	robEntries                  uint32
 	instructionsWrittenPerCycle uint32
 	registerAliasTable          *registeraliastable.RegisterAliasTable
+	loadStoreQueue              *loadstorequeue.LoadStoreQueue
+	// Atomics that wrote into the code, memory is updated before they commit
+	codeStores      map[uint32]bool
+	pageTableStores map[uint32]bool
 }
 
 type RobEntry struct {
 	Operation   *operation.Operation
 	Type        RobType
 	Destination uint32
-	Value       int32
+	Value       int64
+	Size        uint32
 	Cycle       uint32
 }
 
 func New(index uint32, processor iprocessor.IProcessor, startOperationId, robEntries uint32,
-	instructionsWrittenPerCycle uint32, rat *registeraliastable.RegisterAliasTable) *ReorderBuffer {
+	instructionsWrittenPerCycle uint32, rat *registeraliastable.RegisterAliasTable, lsq *loadstorequeue.LoadStoreQueue) *ReorderBuffer {
 	rob := &ReorderBuffer{
 		&reorderBuffer{
 			index:                       index,
 			processor:                   processor,
 			startOperationId:            startOperationId,
+			headOperationId:             startOperationId,
 			buffer:                      map[uint32]RobEntry{},
 			robEntries:                  robEntries,
 			instructionsWrittenPerCycle: instructionsWrittenPerCycle,
 			registerAliasTable:          rat,
+			loadStoreQueue:              lsq,
+			codeStores:                  map[uint32]bool{},
+			pageTableStores:             map[uint32]bool{},
 		},
 	}
 	rob.reorderBuffer.bus = rob.getStorageBus()
@@ -77,8 +101,22 @@ func (this *ReorderBuffer) StartOperationId() uint32 {
 	return this.reorderBuffer.startOperationId
 }
 
-func (this *ReorderBuffer) Buffer() map[uint32]RobEntry {
-	return this.reorderBuffer.buffer
+func (this *ReorderBuffer) HeadOperationId() uint32 {
+	return this.reorderBuffer.headOperationId
+}
+
+func (this *ReorderBuffer) IsHead(op *operation.Operation) bool {
+	return this.HeadOperationId() == op.Id()
+}
+
+func (this *ReorderBuffer) Entries() uint32 {
+	this.reorderBuffer.lock.RLock()
+	defer this.reorderBuffer.lock.RUnlock()
+	return uint32(len(this.reorderBuffer.buffer))
+}
+
+func (this *ReorderBuffer) LoadStoreQueue() *loadstorequeue.LoadStoreQueue {
+	return this.reorderBuffer.loadStoreQueue
 }
 
 func (this *ReorderBuffer) RobEntries() uint32 {
@@ -93,7 +131,11 @@ func (this *ReorderBuffer) RegisterAliasTable() *registeraliastable.RegisterAlia
 	return this.reorderBuffer.registerAliasTable
 }
 
-func (this *ReorderBuffer) LoadRegister(op *operation.Operation, index uint32) uint32 {
+func (this *ReorderBuffer) BytesPerWord() uint32 {
+	return this.Processor().Config().BytesPerWord()
+}
+
+func (this *ReorderBuffer) LoadRegister(op *operation.Operation, index uint32) uint64 {
 	lookupRegister := index
 	// If renaming register enabled
 	if len(this.RegisterAliasTable().Entries()) > 0 {
@@ -103,77 +145,240 @@ func (this *ReorderBuffer) LoadRegister(op *operation.Operation, index uint32) u
 			lookupRegister = ratEntry
 		} else {
 			// Alias does not exist, value was already commited (search on memory)
-			return this.Processor().RegistersMemory().LoadUint32(index * consts.BYTES_PER_WORD)
+			return this.Processor().RegistersMemory().LoadWord(index*this.BytesPerWord(), this.BytesPerWord())
 		}
 	}
 
 	// Search register on ROB
 	robEntry, ok := this.getEntryByDestination(op.Id(), RegisterType, lookupRegister)
 	if ok {
-		return uint32(robEntry.Value)
+		return uint64(robEntry.Value)
 	}
-	return this.Processor().RegistersMemory().LoadUint32(index * consts.BYTES_PER_WORD)
+	return this.Processor().RegistersMemory().LoadWord(index*this.BytesPerWord(), this.BytesPerWord())
 }
 
-func (this *ReorderBuffer) StoreRegister(op *operation.Operation, index, value uint32) {
+func (this *ReorderBuffer) StoreRegister(op *operation.Operation, index uint32, value uint64) {
 
 	dest := index
 	// If renaming register enabled
 	if op.RenamedDestRegister() != -1 {
 		dest = uint32(op.RenamedDestRegister())
 	}
-	this.Buffer()[op.Id()] = RobEntry{
+	this.setEntry(op.Id(), RobEntry{
 		Operation:   op,
 		Type:        RegisterType,
 		Destination: dest,
-		Value:       int32(value),
+		Value:       int64(value),
+		Size:        this.BytesPerWord(),
 		Cycle:       this.Processor().Cycles(),
-	}
+	})
 }
 
 func (this *ReorderBuffer) Allocate(op *operation.Operation) {
 	this.waitStallOperationIfFull(op)
 }
 
-func (this *ReorderBuffer) LoadData(op *operation.Operation, address uint32) uint32 {
-	robEntry, ok := this.getEntryByDestination(op.Id(), MemoryType, address)
-	if ok {
-		return uint32(robEntry.Value)
+func (this *ReorderBuffer) LoadData(op *operation.Operation, wideAddress uint64, size uint32) uint64 {
+	address, ok := this.translateDataAccess(op, wideAddress, size, memory.ReadAccess)
+	if !ok {
+		return 0
 	}
-	return this.Processor().DataMemory().LoadUint32(address)
+	value := this.loadData(op, address, size)
+	this.trainDataPrefetcher(op, address, size)
+	this.traceDataAccess(op, address, size, false, value)
+	return value
 }
 
-func (this *ReorderBuffer) StoreData(op *operation.Operation, address, value uint32) {
+func (this *ReorderBuffer) loadData(op *operation.Operation, address, size uint32) uint64 {
+	if d, ok := this.Processor().Devices().Find(address, size); ok {
+		return this.loadDevice(op, d, address, size)
+	}
+	if this.LoadStoreQueue() != nil {
+		return this.loadDataFromQueue(op, address, size)
+	}
+	forwarded, mask := this.forwardStores(op, address, size)
+	if mask == sizeMask(size) {
+		return forwarded
+	}
+	// Loads not fully forwarded from the ROB wait for the data cache
+	this.Processor().Wait(this.accessDataMemoryLevel(op.Id(), address, size, false))
+	return (this.Processor().DataMemory().LoadWord(address, size) &^ mask) | forwarded
+}
 
-	this.Buffer()[op.Id()] = RobEntry{
+// Bytes of the load written by older stores not committed yet, merged from the oldest to the youngest
+// so the youngest bytes prevail, the mask tells the bytes forwarded
+func (this *ReorderBuffer) forwardStores(op *operation.Operation, address, size uint32) (uint64, uint64) {
+	this.reorderBuffer.lock.RLock()
+	stores := []RobEntry{}
+	for opId, robEntry := range this.reorderBuffer.buffer {
+		if robEntry.Type == MemoryType && opId < op.Id() &&
+			robEntry.Destination < address+size && address < robEntry.Destination+robEntry.Size {
+			stores = append(stores, robEntry)
+		}
+	}
+	this.reorderBuffer.lock.RUnlock()
+	sort.Slice(stores, func(i, j int) bool {
+		return stores[i].Operation.Id() < stores[j].Operation.Id()
+	})
+
+	// Bytes are merged as laid out in memory, so they honour the endianness
+	endianness := this.Processor().Config().DataEndianness()
+	valueBytes := make([]byte, size)
+	maskBytes := make([]byte, size)
+	for _, store := range stores {
+		storeBytes := memory.Encode(uint64(store.Value), store.Size, endianness)
+		for i := uint32(0); i < size; i++ {
+			byteAddress := address + i
+			if byteAddress >= store.Destination && byteAddress < store.Destination+store.Size {
+				valueBytes[i] = storeBytes[byteAddress-store.Destination]
+				maskBytes[i] = 0xFF
+			}
+		}
+	}
+	return memory.Decode(valueBytes, endianness), memory.Decode(maskBytes, endianness)
+}
+
+func (this *ReorderBuffer) loadDataFromQueue(op *operation.Operation, address, size uint32) uint64 {
+	// Bytes written by older stores not committed yet are forwarded from the store queue
+	forwarded, mask := this.LoadStoreQueue().Load(op, address, size)
+	if mask == sizeMask(size) {
+		return forwarded
+	}
+	this.Processor().Wait(this.accessDataMemoryLevel(op.Id(), address, size, false))
+	return (this.Processor().DataMemory().LoadWord(address, size) &^ mask) | forwarded
+}
+
+// Device loads are at the head of the ROB, they bypass the caches and the load/store queue
+func (this *ReorderBuffer) loadDevice(op *operation.Operation, d device.Device, address, size uint32) uint64 {
+	this.Processor().Wait(d.Config().Latency)
+	value := this.Processor().Devices().Read(d, address, size)
+	logger.Collect(" => [RB%d][%03d]: Reading %#08X from device %s[%#X]...", this.Index(), op.Id(), value, d.Name(), address)
+	return value
+}
+
+func (this *ReorderBuffer) StoreData(op *operation.Operation, wideAddress uint64, size uint32, value uint64) {
+	// Device stores are neither forwarded nor checked for ordering, they are written once committed
+	address, ok := this.translateDataAccess(op, wideAddress, size, memory.WriteAccess)
+	if ok {
+		this.traceDataAccess(op, address, size, true, value&sizeMask(size))
+	}
+	if ok && this.LoadStoreQueue() != nil && !this.isDevice(address, size) {
+		this.LoadStoreQueue().Store(op, address, size, value&sizeMask(size))
+	}
+
+	this.setEntry(op.Id(), RobEntry{
 		Operation:   op,
 		Type:        MemoryType,
 		Destination: address,
-		Value:       int32(value),
+		Value:       int64(value & sizeMask(size)),
+		Size:        size,
 		Cycle:       this.Processor().Cycles(),
+	})
+}
+
+func (this *ReorderBuffer) LoadLinked(op *operation.Operation, wideAddress uint64, size uint32) uint64 {
+	address, ok := this.translateDataAccess(op, wideAddress, size, memory.ReadAccess)
+	if !ok {
+		return 0
+	}
+	// Operation is at the head of the ROB, so the reservation is not speculative
+	this.Processor().SetReservation(address)
+	logger.Collect(" => [RB%d][%03d]: Reservation set at %s[%#X]", this.Index(), op.Id(), MemoryType, address)
+	value := this.loadData(op, address, size)
+	this.traceDataAccess(op, address, size, false, value)
+	return value
+}
+
+func (this *ReorderBuffer) StoreConditional(op *operation.Operation, wideAddress uint64, size uint32, value uint64) bool {
+	address, ok := this.translateDataAccess(op, wideAddress, size, memory.WriteAccess)
+	if !ok {
+		return false
+	}
+	success := this.Processor().CheckReservation(address)
+	this.Processor().ClearReservation()
+	if !success {
+		logger.Collect(" => [RB%d][%03d]: Reservation lost at %s[%#X]", this.Index(), op.Id(), MemoryType, address)
+		return false
 	}
+	this.storeAtomic(op, address, size, value)
+	return true
+}
+
+func (this *ReorderBuffer) StoreAtomic(op *operation.Operation, wideAddress uint64, size uint32, value uint64) {
+	address, ok := this.translateDataAccess(op, wideAddress, size, memory.WriteAccess)
+	if !ok {
+		return
+	}
+	this.storeAtomic(op, address, size, value)
+}
+
+func (this *ReorderBuffer) storeAtomic(op *operation.Operation, address, size uint32, value uint64) {
+	// Atomics are executed at the head of the ROB, so memory is updated straight away
+	logger.Collect(" => [RB%d][%03d]: Writing %#08X to %s[%#X] (atomic)...", this.Index(), op.Id(), value, MemoryType, address)
+	this.traceDataAccess(op, address, size, true, value&sizeMask(size))
+	pageTableStore := this.modifiesPageTableAt(address, size)
+	this.storeDataMemory(op.Id(), address, size, value)
+	this.reorderBuffer.lock.Lock()
+	defer this.reorderBuffer.lock.Unlock()
+	if this.Processor().IsCode(address, size) {
+		this.reorderBuffer.codeStores[op.Id()] = true
+	}
+	if pageTableStore {
+		this.reorderBuffer.pageTableStores[op.Id()] = true
+	}
+}
+
+func (this *ReorderBuffer) ReadCounter(op *operation.Operation, index uint32) uint32 {
+	// Operation is at the head of the ROB, so every older operation is already counted
+	value := this.Processor().PerformanceCounter(index)
+	if index == consts.PMC_INSTRUCTIONS {
+		// Completion log is written back asynchronously, the operation id is already exact
+		value = op.Id()
+	}
+	logger.Collect(" => [RB%d][%03d]: Reading counter PMC%d = %d", this.Index(), op.Id(), index, value)
+	return value
+}
+
+func (this *ReorderBuffer) Complete(op *operation.Operation) {
+
+	this.setEntry(op.Id(), RobEntry{
+		Operation: op,
+		Type:      NilType,
+		Cycle:     this.Processor().Cycles(),
+	})
 }
 
 func (this *ReorderBuffer) IncrementProgramCounter(op *operation.Operation, value int32) {
 
-	this.Buffer()[op.Id()] = RobEntry{
+	this.setEntry(op.Id(), RobEntry{
 		Operation:   op,
 		Type:        ProgramCounterType,
 		Destination: OffsetType,
-		Value:       value,
+		Value:       int64(value),
 		Cycle:       this.Processor().Cycles(),
-	}
+	})
 }
 
 func (this *ReorderBuffer) SetProgramCounter(op *operation.Operation, value uint32) {
 
-	this.Buffer()[op.Id()] = RobEntry{
+	this.setEntry(op.Id(), RobEntry{
 		Operation:   op,
 		Type:        ProgramCounterType,
 		Destination: AbsoluteType,
-		Value:       int32(value),
+		Value:       int64(value),
 		Cycle:       this.Processor().Cycles(),
-	}
+	})
+}
+
+func (this *ReorderBuffer) SetupLoop(op *operation.Operation, count, end uint32) {
+
+	this.setEntry(op.Id(), RobEntry{
+		Operation:   op,
+		Type:        LoopType,
+		Destination: end,
+		Value:       int64(count),
+		Cycle:       this.Processor().Cycles(),
+	})
 }
 
 func (this *ReorderBuffer) Run(commonDataBus channel.Channel, recoveryBus channel.Channel) {
@@ -209,17 +414,52 @@ func (this *ReorderBuffer) Run(commonDataBus channel.Channel, recoveryBus channe
 
 			// Commit in order, if missing an operation, wait for it
 			computedAddress := uint32(0)
+			storeFlush := false
+			var fault error
 			robEntries := []RobEntry{}
-			for robEntry, exists := this.Buffer()[opId]; exists; robEntry, exists = this.Buffer()[opId] {
+			for robEntry, exists := this.getEntry(opId); exists; robEntry, exists = this.getEntry(opId) {
 				if uint32(len(robEntries)) >= this.InstructionsWrittenPerCycle() {
 					break
 				}
 				// Ensure we can write results the next cycle result was written into ROB
 				if this.Processor().Cycles() > robEntry.Cycle+1 {
+					// Faults are delivered in order, neither the operation nor younger ones are committed
+					if robEntry.Operation.Fault() != nil {
+						fault = robEntry.Operation.Fault()
+						break
+					}
+					// Fetch waits for every loop to be set up, so the committed loops are the ones enclosing it
+					if robEntry.Type == LoopType && robEntry.Value > 0 && this.Processor().HardwareLoopDepthReached() {
+						reason := fmt.Sprintf("more than %d nested loops", this.Processor().Config().HardwareLoopDepth())
+						if this.Processor().Config().HardwareLoopDepth() == 0 {
+							reason = "hardware loops are disabled"
+						}
+						fault = errors.New(fmt.Sprintf("Hardware loop fault: %s (PC: %#04X, OpId: %d)", reason, robEntry.Operation.Address(), opId))
+						break
+					}
+					// Loads that read stale data are not committed, they are fetched again
+					if this.LoadStoreQueue() != nil && this.LoadStoreQueue().HasViolation(robEntry.Operation) {
+						logger.Collect(" => [RB%d][%03d]: Memory ordering violation, replaying from %#04X", this.Index(), opId, robEntry.Operation.Address())
+						this.Processor().LogOrderingViolation()
+						this.Processor().LogLoadReplay()
+						misprediction, computedAddress = true, robEntry.Operation.Address()
+						break
+					}
 					// Check for misprediction
-					misprediction, computedAddress = this.checkForMisprediction(this.Buffer()[opId], robEntries)
+					misprediction, computedAddress = this.checkForMisprediction(robEntry, robEntries)
 					// Decrement speculative jumps
 					this.Processor().DecrementSpeculativeJump()
+					// Stores into the code flush every younger operation, they may have been fetched stale
+					if !misprediction && this.modifiesCode(robEntry) {
+						logger.Collect(" => [RB%d][%03d]: Store into the code, flushing younger operations", this.Index(), opId)
+						this.Processor().LogCodeModification()
+						misprediction, storeFlush = true, true
+					}
+					// Stores into the page table flush every younger operation, they may have been translated stale
+					if !misprediction && this.modifiesPageTable(robEntry) {
+						logger.Collect(" => [RB%d][%03d]: Store into the page table, flushing younger operations", this.Index(), opId)
+						misprediction, storeFlush = true, true
+					}
 					// Add to queue for commit
 					robEntries = append(robEntries, robEntry)
 					opId += 1
@@ -230,6 +470,19 @@ func (this *ReorderBuffer) Run(commonDataBus channel.Channel, recoveryBus channe
 				}
 			}
 			this.commitRobEntries(robEntries)
+			this.reorderBuffer.headOperationId = opId
+			if storeFlush {
+				// Fetch restarts right after the store (or at the start of a hardware loop)
+				computedAddress = this.Processor().ProgramCounter()
+			}
+			if fault != nil {
+				// Older operations are written back before stopping
+				this.Processor().Wait(consts.WRITEBACK_CYCLES + 1)
+				this.Processor().RaiseFault(fault)
+			}
+			if this.Processor().Fault() != nil {
+				return
+			}
 			if misprediction {
 				this.Processor().Wait(consts.WRITEBACK_CYCLES)
 				recoveryBus.Add(operation.New(opId, computedAddress))
@@ -239,15 +492,40 @@ func (this *ReorderBuffer) Run(commonDataBus channel.Channel, recoveryBus channe
 	}()
 }
 
+// Stores into the code, atomic ones were already written when they executed
+func (this *ReorderBuffer) modifiesCode(robEntry RobEntry) bool {
+	if robEntry.Type == MemoryType {
+		return this.Processor().IsCode(robEntry.Destination, robEntry.Size)
+	}
+	this.reorderBuffer.lock.RLock()
+	defer this.reorderBuffer.lock.RUnlock()
+	return this.reorderBuffer.codeStores[robEntry.Operation.Id()]
+}
+
+// Stores into page table entries already walked, atomic ones were already written when they executed
+func (this *ReorderBuffer) modifiesPageTable(robEntry RobEntry) bool {
+	if robEntry.Type == MemoryType {
+		return this.modifiesPageTableAt(robEntry.Destination, robEntry.Size)
+	}
+	this.reorderBuffer.lock.RLock()
+	defer this.reorderBuffer.lock.RUnlock()
+	return this.reorderBuffer.pageTableStores[robEntry.Operation.Id()]
+}
+
+func (this *ReorderBuffer) modifiesPageTableAt(address, size uint32) bool {
+	return this.Processor().MMU() != nil && !this.isDevice(address, size) && this.Processor().MMU().IsPageTableStore(address, size)
+}
+
 func (this *ReorderBuffer) waitStallOperationIfFull(op *operation.Operation) {
-	for uint32(len(this.Buffer())) >= this.RobEntries() {
+	for this.Entries() >= this.RobEntries() {
 		lastCompletedOpId := this.Processor().LastOperationIdCompleted()
 		if op.Id() == lastCompletedOpId+1 {
 			logger.Collect(" => [RB%d][%03d]: Writing latest instruction.", this.Index(), op.Id())
 			return
 		}
 		logger.Collect(" => [RB%d][%03d]: ROB is full, wait for free entries. Current: %d, Max: %d, LastOpId: %d...",
-			this.Index(), op.Id(), len(this.Buffer()), this.RobEntries(), lastCompletedOpId)
+			this.Index(), op.Id(), this.Entries(), this.RobEntries(), lastCompletedOpId)
+		this.Processor().LogRobFullStall()
 		this.Processor().Wait(1)
 	}
 }
@@ -261,31 +539,35 @@ func (this *ReorderBuffer) checkForMisprediction(targetEntry RobEntry, cachedEnt
 
 	// If operation does not have a predicted address, then return
 	if op.PredictedAddress() == -1 {
-		this.Processor().LogBranchInstruction(op.Address(), op.Instruction().Info.IsConditionalBranch(), false, op.Taken())
+		computedAddress := this.getNextProgramCounter(targetEntry, op.NextAddress())
+		this.Processor().LogBranchInstruction(op.Id(), op.Address(), op.Instruction().Info.IsConditionalBranch(), false, op.Taken(), computedAddress)
+		this.traceBranch(op, computedAddress)
 		return false, 0
 	}
 
 	// If predicted address is equal to the computed address, then return
-	computedAddress := this.getNextProgramCounter(targetEntry, this.getCachedProgramCounter(cachedEntries))
+	computedAddress := this.getNextProgramCounter(targetEntry, op.NextAddress())
 	failed := computedAddress != uint32(op.PredictedAddress())
 	if failed {
 		logger.Collect(" => [RB%d][%03d]: Misprediction found, it was predicted: %#04X and computed: %#04X",
 			this.Index(), targetEntry.Operation.Id(), op.PredictedAddress(), computedAddress)
 	}
-	this.Processor().LogBranchInstruction(op.Address(), op.Instruction().Info.IsConditionalBranch(), failed, op.Taken())
+	this.Processor().LogBranchInstruction(op.Id(), op.Address(), op.Instruction().Info.IsConditionalBranch(), failed, op.Taken(), computedAddress)
+	this.traceBranch(op, computedAddress)
 	return failed, computedAddress
 }
 
-func (this *ReorderBuffer) getCachedProgramCounter(cachedEntries []RobEntry) uint32 {
-	pc := this.Processor().ProgramCounter()
-	for _, robEntry := range cachedEntries {
-		if robEntry.Type == ProgramCounterType {
-			pc = this.getNextProgramCounter(robEntry, pc)
-		}
-		// Increment program counter
-		pc += consts.BYTES_PER_WORD
+func (this *ReorderBuffer) traceBranch(op *operation.Operation, target uint32) {
+	if this.Processor().BranchTrace() == nil || !op.Instruction().Info.IsConditionalBranch() {
+		return
 	}
-	return pc + consts.BYTES_PER_WORD
+	this.Processor().BranchTrace().Add(branchtrace.Record{
+		ProgramCounter: op.Address(),
+		Taken:          op.Taken(),
+		Target:         target,
+		Instruction:    op.Instruction().ToUint32(),
+		Retired:        this.Processor().InstructionsCompletedCounter(),
+	})
 }
 
 func (this *ReorderBuffer) commitRobEntries(robEntries []RobEntry) {
@@ -293,6 +575,9 @@ func (this *ReorderBuffer) commitRobEntries(robEntries []RobEntry) {
 	// Commit results in order
 	opIds := []uint32{}
 	for _, robEntry := range robEntries {
+		if this.Processor().Fault() != nil {
+			break
+		}
 		opIds = append(opIds, robEntry.Operation.Id())
 		logger.Collect(" => [RB%d][%03d]: Commiting operation %d...", this.Index(), robEntry.Operation.Id(), robEntry.Operation.Id())
 		this.commitRobEntry(robEntry, startCycles)
@@ -323,61 +608,287 @@ func (this *ReorderBuffer) commitRobEntry(robEntry RobEntry, startCycles uint32)
 		}
 
 		logger.Collect(" => [RB%d][%03d]: Writing %#08X to %s%d...", this.Index(), opId, robEntry.Value, robEntry.Type, dest)
-		this.Processor().RegistersMemory().StoreUint32(dest*consts.BYTES_PER_WORD, uint32(robEntry.Value))
+		this.Processor().RegistersMemory().StoreWord(dest*robEntry.Size, robEntry.Size, uint64(robEntry.Value))
 	} else if robEntry.Type == MemoryType {
 		logger.Collect(" => [RB%d][%03d]: Writing %#08X to %s[%#X]...", this.Index(), opId, robEntry.Value, robEntry.Type, robEntry.Destination)
-		this.Processor().DataMemory().StoreUint32(robEntry.Destination, uint32(robEntry.Value))
-	} else {
+		this.storeDataMemory(opId, robEntry.Destination, robEntry.Size, uint64(robEntry.Value))
+		this.trainDataPrefetcher(robEntry.Operation, robEntry.Destination, robEntry.Size)
+	} else if robEntry.Type == ProgramCounterType {
 		this.Processor().SetProgramCounter(this.getNextProgramCounter(robEntry, this.Processor().ProgramCounter()))
+	} else if robEntry.Type == LoopType {
+		logger.Collect(" => [RB%d][%03d]: Setting hardware loop of %d iterations until %#04X...", this.Index(), opId, robEntry.Value, robEntry.Destination)
+		if robEntry.Value == 0 {
+			// Empty loop, skip the whole body
+			this.Processor().SetProgramCounter(robEntry.Destination - robEntry.Operation.Size())
+		} else {
+			this.Processor().PushHardwareLoop(robEntry.Operation.NextAddress(), robEntry.Destination, uint32(robEntry.Value))
+		}
+	}
+
+	// Log memory and atomic operations
+	opcode := robEntry.Operation.Instruction().Info.Opcode
+	if set.IsLoad(opcode) || set.IsStore(opcode) {
+		this.Processor().LogMemoryInstruction(set.IsLoad(opcode), set.IsStore(opcode))
+	}
+	if set.IsAtomic(opcode) {
+		failed := opcode == set.OP_SC && robEntry.Value == 0
+		this.Processor().LogAtomicInstruction(opcode == set.OP_FENCE, failed)
 	}
 
 	// Increment program counter
-	this.Processor().IncrementProgramCounter(consts.BYTES_PER_WORD)
+	this.Processor().IncrementProgramCounter(int32(robEntry.Operation.Size()))
+
+	// End of a hardware loop body jumps back to its start (no branch in the pipeline)
+	start, looping := this.Processor().CommitHardwareLoop(robEntry.Operation.NextAddress())
+	if looping {
+		this.Processor().SetProgramCounter(start)
+	}
 	logger.Collect(" => [RB%d][%03d]: PC = %#04X", this.Index(), opId, this.Processor().ProgramCounter())
+	if robEntry.Type == ProgramCounterType {
+		this.checkProgramCounter(robEntry.Operation)
+	}
+
+	// Traced accesses of the operation are not speculative anymore
+	if this.Processor().MemoryTrace() != nil {
+		this.Processor().MemoryTrace().Commit(opId, robEntry.Operation.Address())
+	}
+
+	// Release ROB & load/store queue entries
+	this.removeEntry(opId)
+	if this.LoadStoreQueue() != nil {
+		this.LoadStoreQueue().Release(robEntry.Operation)
+	}
+}
+
+func (this *ReorderBuffer) storeDataMemory(opId, address, size uint32, value uint64) {
+	// Any store to a reserved address breaks the reservation of a load-linked
+	if this.Processor().CheckReservation(address) {
+		this.Processor().ClearReservation()
+	}
+	// Device writes bypass the caches and the data memory
+	if d, ok := this.Processor().Devices().Find(address, size); ok {
+		logger.Collect(" => [RB%d][%03d]: Writing %#08X to device %s[%#X]...", this.Index(), opId, value, d.Name(), address)
+		this.Processor().Devices().Write(d, address, size, value)
+		return
+	}
+	// Stores are buffered once committed, so they do not wait for the data cache
+	this.accessDataMemoryLevel(opId, address, size, true)
+	this.Processor().DataMemory().StoreWord(address, size, value)
+	// Instruction cache is kept coherent with the code written
+	if this.Processor().IsCode(address, size) && this.Processor().InstructionCache() != nil {
+		this.Processor().InstructionCache().Invalidate(address, size)
+	}
+	// TLBs are kept coherent with the page table written
+	if this.Processor().MMU() != nil {
+		this.Processor().MMU().StorePageTable(address, size)
+	}
+}
+
+// Virtual addresses are translated before being checked, returns the physical address
+// Memory is addressed with 32 bits, wider addresses of the 64-bit datapath fault instead of wrapping around
+func (this *ReorderBuffer) translateDataAccess(op *operation.Operation, wideAddress uint64, size uint32, access memory.AccessType) (uint32, bool) {
+	address := uint32(wideAddress)
+	if wideAddress>>32 != 0 {
+		this.setFault(op, &memory.Fault{Address: wideAddress, Size: size, Access: access, Reason: "beyond the 32-bit address space"})
+		return address, false
+	}
+	if this.Processor().MMU() == nil {
+		return address, this.checkDataAccess(op, address, size, access)
+	}
+	physical, cycles, err := this.Processor().MMU().TranslateData(address, size, access)
+	logger.Collect(" => [RB%d][%03d]: Translating %s[%#X] to %s[%#X] (%d cycles)", this.Index(), op.Id(), MemoryType, address, MemoryType, physical, cycles)
+	this.Processor().Wait(cycles)
+	if err != nil {
+		this.setFault(op, err.(*memory.Fault))
+		return address, false
+	}
+	return physical, this.checkDataAccess(op, physical, size, access)
+}
+
+// Faulting accesses do not touch memory, the fault is attached to the operation until it commits
+func (this *ReorderBuffer) checkDataAccess(op *operation.Operation, address, size uint32, access memory.AccessType) bool {
+	err := this.Processor().DataMemory().Check(address, size, access)
+	if d, ok := this.Processor().Devices().Find(address, size); ok {
+		// Device ranges may lie out of the data memory, but accesses cannot cross their boundaries
+		err = nil
+		cfg := d.Config()
+		if address < cfg.Start || address+size > cfg.Start+cfg.Size {
+			err = &memory.Fault{Address: uint64(address), Size: size, Access: access,
+				Reason: fmt.Sprintf("crossing device %s [%#04X, %#04X)", cfg.Name, cfg.Start, cfg.Start+cfg.Size)}
+		}
+	}
+	if err == nil {
+		return true
+	}
+	this.setFault(op, err.(*memory.Fault))
+	return false
+}
+
+func (this *ReorderBuffer) setFault(op *operation.Operation, fault *memory.Fault) {
+	fault.OperationId = op.Id()
+	fault.ProgramCounter = op.Address()
+	op.SetFault(fault)
+	logger.Collect(" => [RB%d][%03d]: %s", this.Index(), op.Id(), fault.Error())
+}
+
+func (this *ReorderBuffer) isDevice(address, size uint32) bool {
+	_, ok := this.Processor().Devices().Find(address, size)
+	return ok
+}
 
-	// Release ROB entry
-	delete(this.Buffer(), opId)
+func (this *ReorderBuffer) traceDataAccess(op *operation.Operation, address, size uint32, write bool, value uint64) {
+	if this.Processor().MemoryTrace() == nil {
+		return
+	}
+	this.Processor().MemoryTrace().Add(memorytrace.Record{
+		Cycle:          this.Processor().Cycles(),
+		OperationId:    op.Id(),
+		ProgramCounter: op.Address(),
+		Address:        address,
+		Size:           size,
+		Write:          write,
+		Value:          value,
+	})
+}
+
+func (this *ReorderBuffer) checkProgramCounter(op *operation.Operation) {
+	// Virtual program counters are checked when they are fetched
+	if this.Processor().MMU() != nil {
+		return
+	}
+	programCounter := this.Processor().ProgramCounter()
+	err := this.Processor().InstructionsMemory().Check(programCounter, this.Processor().Config().InstructionAlignment(), memory.ExecuteAccess)
+	if err != nil {
+		fault := err.(*memory.Fault)
+		fault.OperationId = op.Id()
+		fault.ProgramCounter = op.Address()
+		this.Processor().RaiseFault(fault)
+	}
+}
+
+func (this *ReorderBuffer) accessDataMemoryLevel(opId, address, size uint32, write bool) uint32 {
+	// Accesses wait for a free port and free banks of the data memory
+	if this.Processor().MemoryPorts() != nil {
+		delay := this.Processor().MemoryPorts().Arbitrate(address, size, write, this.Processor().Cycles())
+		if delay > 0 {
+			logger.Collect(" => [RB%d][%03d]: Waiting memory port for %s[%#X] (%d cycles)", this.Index(), opId, MemoryType, address, delay)
+			this.Processor().Wait(delay)
+		}
+	}
+	level := this.Processor().DataMemoryLevel()
+	if level == nil {
+		return 0
+	}
+	var latency uint32
+	var hit bool
+	if write {
+		latency, hit = level.Write(address, size)
+	} else {
+		latency, hit = level.Read(address, size)
+	}
+	logger.Collect(" => [RB%d][%03d]: %s %s[%#X] hit: %v (%d cycles)", this.Index(), opId, level.Name(), MemoryType, address, hit, latency)
+	if this.Processor().DataPrefetcher() != nil {
+		// Hits on lines still being prefetched wait for the fill
+		late := this.Processor().DataPrefetcher().Demand(address, hit, this.Processor().Cycles())
+		if late > 0 {
+			logger.Collect(" => [RB%d][%03d]: Late prefetch of %s[%#X] (%d cycles)", this.Index(), opId, MemoryType, address, late)
+		}
+		latency += late
+	}
+	return latency
+}
+
+// Prefetchers are trained with the address stream of the load/store units, device accesses excluded,
+// after the demand access so it never finds the prefetches it triggered (loads execute, stores commit)
+func (this *ReorderBuffer) trainDataPrefetcher(op *operation.Operation, address, size uint32) {
+	if this.Processor().DataPrefetcher() == nil || this.isDevice(address, size) {
+		return
+	}
+	for _, line := range this.Processor().DataPrefetcher().Train(op.Address(), address, this.Processor().Cycles()) {
+		logger.Collect(" => [RB%d][%03d]: Prefetching %s[%#X]...", this.Index(), op.Id(), MemoryType, line)
+	}
+}
+
+func sizeMask(size uint32) uint64 {
+	if size >= consts.BYTES_PER_DOUBLE {
+		return ^uint64(0)
+	}
+	return (uint64(1) << (size * consts.BITS_PER_BYTE)) - 1
 }
 
 func (this *ReorderBuffer) getNextProgramCounter(robEntry RobEntry, programCounter uint32) uint32 {
 	if robEntry.Destination == AbsoluteType {
 		return uint32(robEntry.Value)
 	} else {
-		return uint32(int32(programCounter) + robEntry.Value)
+		return uint32(int32(programCounter) + int32(robEntry.Value))
 	}
 }
 
 func (this *ReorderBuffer) getEntryByDestination(operationId uint32, robType RobType, destination uint32) (RobEntry, bool) {
+	this.reorderBuffer.lock.RLock()
+	defer this.reorderBuffer.lock.RUnlock()
+
 	maxOpId := int32(-1)
-	for opId, value := range this.Buffer() {
+	for opId, value := range this.reorderBuffer.buffer {
 		if value.Type == robType && value.Destination == destination && int32(opId) >= maxOpId && opId <= operationId {
 			maxOpId = int32(opId)
 		}
 	}
 	if maxOpId >= 0 {
-		return this.Buffer()[uint32(maxOpId)], true
+		return this.reorderBuffer.buffer[uint32(maxOpId)], true
 	}
 	return RobEntry{}, false
 }
 
+// Entries are written concurrently by the execution units
+func (this *ReorderBuffer) getEntry(opId uint32) (RobEntry, bool) {
+	this.reorderBuffer.lock.RLock()
+	defer this.reorderBuffer.lock.RUnlock()
+	robEntry, ok := this.reorderBuffer.buffer[opId]
+	return robEntry, ok
+}
+
+func (this *ReorderBuffer) setEntry(opId uint32, robEntry RobEntry) {
+	this.reorderBuffer.lock.Lock()
+	defer this.reorderBuffer.lock.Unlock()
+	this.reorderBuffer.buffer[opId] = robEntry
+}
+
+func (this *ReorderBuffer) removeEntry(opId uint32) {
+	this.reorderBuffer.lock.Lock()
+	defer this.reorderBuffer.lock.Unlock()
+	delete(this.reorderBuffer.buffer, opId)
+}
+
 func (this *ReorderBuffer) getStorageBus() *storagebus.StorageBus {
 
 	return &storagebus.StorageBus{
 
 		// Registers handlers
-		LoadRegister: func(op *operation.Operation, index uint32) uint32 {
+		LoadRegister: func(op *operation.Operation, index uint32) uint64 {
 			return this.LoadRegister(op, index)
 		},
-		StoreRegister: func(op *operation.Operation, index, value uint32) {
+		StoreRegister: func(op *operation.Operation, index uint32, value uint64) {
 			this.StoreRegister(op, index, value)
 		},
 
 		// Data Memory handlers
-		LoadData: func(op *operation.Operation, address uint32) uint32 {
-			return this.LoadData(op, address)
+		LoadData: func(op *operation.Operation, address uint64, size uint32) uint64 {
+			return this.LoadData(op, address, size)
 		},
-		StoreData: func(op *operation.Operation, address, value uint32) {
-			this.StoreData(op, address, value)
+		StoreData: func(op *operation.Operation, address uint64, size uint32, value uint64) {
+			this.StoreData(op, address, size, value)
+		},
+
+		// Atomic Data Memory handlers
+		LoadLinked: func(op *operation.Operation, address uint64, size uint32) uint64 {
+			return this.LoadLinked(op, address, size)
+		},
+		StoreConditional: func(op *operation.Operation, address uint64, size uint32, value uint64) bool {
+			return this.StoreConditional(op, address, size, value)
+		},
+		StoreAtomic: func(op *operation.Operation, address uint64, size uint32, value uint64) {
+			this.StoreAtomic(op, address, size, value)
 		},
 
 		// Program Counter handlers
@@ -387,5 +898,21 @@ func (this *ReorderBuffer) getStorageBus() *storagebus.StorageBus {
 		SetProgramCounter: func(op *operation.Operation, value uint32) {
 			this.SetProgramCounter(op, value)
 		},
+		SetupLoop: func(op *operation.Operation, count, end uint32) {
+			this.SetupLoop(op, count, end)
+		},
+
+		// Performance counters handlers
+		ReadCounter: func(op *operation.Operation, index uint32) uint32 {
+			return this.ReadCounter(op, index)
+		},
+
+		// Ordering handlers
+		Complete: func(op *operation.Operation) {
+			this.Complete(op)
+		},
+		IsHead: func(op *operation.Operation) bool {
+			return this.IsHead(op)
+		},
 	}
 }
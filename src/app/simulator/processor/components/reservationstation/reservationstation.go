@@ -6,6 +6,7 @@ import (
 	"app/logger"
 	"app/simulator/iprocessor"
 	"app/simulator/processor/components/channel"
+	"app/simulator/processor/components/loadstorequeue"
 	"app/simulator/processor/components/registeraliastable"
 	"app/simulator/processor/components/storagebus"
 	"app/simulator/processor/consts"
@@ -31,6 +32,7 @@ const (
 	MemoryType   OperandType = "MEM"
 	RegisterType OperandType = "REG"
 	RatType      OperandType = "RAT"
+	FenceType    OperandType = "FENCE"
 )
 
 type Operand struct {
@@ -51,6 +53,10 @@ func newRegisterRatOp(register Register, ratEntry int32) Operand {
 	return Operand{Type: RatType, Register: register, RatEntry: ratEntry}
 }
 
+func newFenceDep(operationId uint32) Operand {
+	return Operand{Type: FenceType, Register: Register(operationId), RatEntry: INVALID_INDEX}
+}
+
 func newNilDep() Operand {
 	return Operand{Type: NilType, Register: Register(INVALID_INDEX), RatEntry: INVALID_INDEX}
 }
@@ -74,6 +80,8 @@ func (this Operand) String() string {
 		return fmt.Sprintf("R%d", this.Register)
 	case RatType:
 		return fmt.Sprintf("R%d(RAT%d)", this.Register, this.RatEntry)
+	case FenceType:
+		return fmt.Sprintf("FENCE(%d)", this.Register)
 	default:
 		return fmt.Sprintf("%v", this.Type)
 	}
@@ -218,6 +226,11 @@ func (this *ReservationStation) runScheduler() {
 		entryIndex := this.getNextIndexFreeEntry()
 		dest, valueOperands, memoryOperands := this.getComponentsFromInstruction(op.Instruction())
 
+		// Memory dependencies of plain loads & stores are resolved by the load/store queue
+		if this.Processor().Config().LoadStoreQueue() && loadstorequeue.IsQueued(op.Instruction().Info.Opcode) {
+			memoryOperands = []Register{}
+		}
+
 		// Convert to operand objects
 		ops := []Operand{}
 		for _, register := range memoryOperands {
@@ -243,7 +256,7 @@ func (this *ReservationStation) runScheduler() {
 			}
 		}
 
-		dependencies := this.getDependencies(op.Id(), regDestRat, ops)
+		dependencies := this.getDependencies(op, regDestRat, ops)
 		logger.Collect(" => [RS%d][%03d]: Adding op to entry %d [D: %v, O's: %v, V's: %v] ..",
 			this.Index(), op.Id(), entryIndex, regDestRat, ops, dependencies)
 
@@ -303,6 +316,12 @@ func (this *ReservationStation) runCommonBusListener(commonDataBus channel.Chann
 				this.releaseOperation(operand)
 			}
 		}
+
+		// Release memory operations ordered after an atomic or fence
+		if set.IsAtomic(op.Instruction().Info.Opcode) {
+			logger.Collect(" => [RS%d][%03d]: Memory ordering %v resolved", this.Index(), op.Id(), newFenceDep(op.Id()))
+			this.releaseOperation(newFenceDep(op.Id()))
+		}
 		<-this.Lock()
 	}
 }
@@ -320,6 +339,27 @@ func (this *ReservationStation) dispatchOperation(entryIndex EntryIndex, op *ope
 		// Wait dispatch cycles
 		startCycles := this.Processor().Cycles()
 		this.Processor().Wait(consts.DISPATCH_CYCLES)
+		// Serializing operations are only sent to execution once they reach the head of the ROB
+		if set.IsSerializing(op.Instruction().Info.Opcode) {
+			stallCycles := this.Processor().Cycles()
+			for !this.Bus().IsHead(op) && this.reservationStation.isActive {
+				this.Processor().Wait(1)
+			}
+			logger.Collect(" => [RS%d][%03d]: Operation reached the head of the ROB after %d cycles", this.Index(), op.Id(), this.Processor().Cycles()-stallCycles)
+			this.Processor().LogSerializationStall(this.Processor().Cycles() - stallCycles)
+		}
+		// Device reads have side effects, so loads from a device are not sent to execution while speculative
+		if this.isDeviceLoad(op) {
+			stallCycles := this.Processor().Cycles()
+			for !this.Bus().IsHead(op) && this.reservationStation.isActive {
+				this.Processor().Wait(1)
+			}
+			// Squashed loads do not count as stalled
+			if this.reservationStation.isActive {
+				logger.Collect(" => [RS%d][%03d]: Device load reached the head of the ROB after %d cycles", this.Index(), op.Id(), this.Processor().Cycles()-stallCycles)
+				this.Processor().LogDeviceStall(this.Processor().Cycles() - stallCycles)
+			}
+		}
 		// Log completion
 		this.Processor().LogEvent(consts.DISPATCH_EVENT, this.Index(), op.Id(), startCycles)
 		// Send data to execution unit in a go routine, when execution unit is available
@@ -327,12 +367,38 @@ func (this *ReservationStation) dispatchOperation(entryIndex EntryIndex, op *ope
 	}()
 }
 
+func (this *ReservationStation) isDeviceLoad(op *operation.Operation) bool {
+	if len(this.Processor().Devices().Devices()) == 0 {
+		return false
+	}
+	size := uint32(consts.BYTES_PER_WORD)
+	switch op.Instruction().Info.Opcode {
+	case set.OP_LW:
+	case set.OP_LD:
+		size = consts.BYTES_PER_DOUBLE
+	default:
+		return false
+	}
+	// Operands are ready, so the address is already known
+	data := op.Instruction().Data.(*data.DataI)
+	address := uint32(this.Bus().LoadRegister(op, data.RegisterS.ToUint32())) + data.Immediate.ToUint32()
+	if this.Processor().MMU() != nil {
+		physical, ok := this.Processor().MMU().Probe(address)
+		if !ok {
+			return false
+		}
+		address = physical
+	}
+	_, ok := this.Processor().Devices().Find(address, size)
+	return ok
+}
+
 func (this *ReservationStation) releaseOperation(operand Operand) {
 
 	// Remove dependencies from entries
 	for entryIndex, entry := range this.Entries() {
 		if !entry.Free && !entry.Busy {
-			dependencies := this.getDependencies(entry.Operation.Id(), entry.Destination, entry.Operands)
+			dependencies := this.getDependencies(entry.Operation, entry.Destination, entry.Operands)
 			this.Entries()[entryIndex].Dependencies = dependencies
 			//logger.Collect(" => [RS%d][%03d]: Dependencies op of entry %d [O: %v, V's: %v]...",
 			//	this.Index(), entry.Operation.Id(), entryIndex, entry.Operands, dependencies)
@@ -344,8 +410,9 @@ func (this *ReservationStation) releaseOperation(operand Operand) {
 	}
 }
 
-func (this *ReservationStation) getDependencies(operationId uint32, destRegister Operand, targetOperands []Operand) []Operand {
+func (this *ReservationStation) getDependencies(op *operation.Operation, destRegister Operand, targetOperands []Operand) []Operand {
 	dependencies := []Operand{}
+	operationId := op.Id()
 
 	// If renaming registers enabled (RAT) is not enabled, check target destination
 	if len(this.RegisterAliasTable().Entries()) == 0 {
@@ -385,6 +452,11 @@ func (this *ReservationStation) getDependencies(operationId uint32, destRegister
 					dependencies = append(dependencies, targetOperand)
 				}
 			}
+
+			// Memory ordering, no memory access is issued before an older atomic or fence
+			if set.AccessesMemory(op.Instruction().Info.Opcode) && set.IsAtomic(entry.Operation.Instruction().Info.Opcode) {
+				dependencies = append(dependencies, newFenceDep(entry.Operation.Id()))
+			}
 		}
 	}
 	return dependencies
@@ -443,12 +515,16 @@ func (this *ReservationStation) getComponentsFromInstruction(instruction *instru
 			return Register(INVALID_INDEX), []Register{Register(data.RegisterD.ToUint32()), Register(data.RegisterS.ToUint32())}, []Register{}
 		} else {
 			switch instruction.Info.Opcode {
-			case set.OP_LW:
+			case set.OP_LW, set.OP_LD, set.OP_LL:
 				return Register(data.RegisterD.ToUint32()), []Register{Register(data.RegisterS.ToUint32())}, []Register{Register(data.RegisterS.ToUint32())}
-			case set.OP_LLI, set.OP_LUI:
+			case set.OP_SC:
+				return Register(data.RegisterD.ToUint32()), []Register{Register(data.RegisterS.ToUint32()), Register(data.RegisterD.ToUint32())}, []Register{Register(data.RegisterS.ToUint32())}
+			case set.OP_LLI, set.OP_LUI, set.OP_RDCYCLE, set.OP_RDINSTRET, set.OP_RDPMC:
 				return Register(data.RegisterD.ToUint32()), []Register{}, []Register{}
-			case set.OP_SW:
+			case set.OP_SW, set.OP_SD:
 				return Register(INVALID_INDEX), []Register{Register(data.RegisterS.ToUint32()), Register(data.RegisterD.ToUint32())}, []Register{Register(data.RegisterD.ToUint32())}
+			case set.OP_LOOP:
+				return Register(INVALID_INDEX), []Register{Register(data.RegisterD.ToUint32())}, []Register{}
 			case set.OP_SLI, set.OP_SUI:
 				return Register(INVALID_INDEX), []Register{Register(data.RegisterD.ToUint32())}, []Register{Register(data.RegisterD.ToUint32())}
 			default:
@@ -457,7 +533,14 @@ func (this *ReservationStation) getComponentsFromInstruction(instruction *instru
 		}
 	} else if instruction.Info.Type == data.TypeR {
 		data := instruction.Data.(*data.DataR)
-		return Register(data.RegisterD.ToUint32()), []Register{Register(data.RegisterS.ToUint32()), Register(data.RegisterT.ToUint32())}, []Register{}
+		switch instruction.Info.Opcode {
+		case set.OP_CAS:
+			return Register(data.RegisterD.ToUint32()), []Register{Register(data.RegisterS.ToUint32()), Register(data.RegisterT.ToUint32()), Register(data.RegisterD.ToUint32())}, []Register{Register(data.RegisterS.ToUint32())}
+		case set.OP_AMOADD, set.OP_AMOSWAP:
+			return Register(data.RegisterD.ToUint32()), []Register{Register(data.RegisterS.ToUint32()), Register(data.RegisterT.ToUint32())}, []Register{Register(data.RegisterS.ToUint32())}
+		default:
+			return Register(data.RegisterD.ToUint32()), []Register{Register(data.RegisterS.ToUint32()), Register(data.RegisterT.ToUint32())}, []Register{}
+		}
 	}
 	return INVALID_INDEX, nil, nil
 }
@@ -471,11 +554,13 @@ func (this *ReservationStation) getComponentsFromInstruction(instruction *instru
 			return Register(INVALID_INDEX), []Register{Register(data.RegisterD.ToUint32()), Register(data.RegisterS.ToUint32())}, []Register{}
 		} else {
 			switch instruction.Info.Opcode {
-			case set.OP_LW:
+			case set.OP_LW, set.OP_LD, set.OP_LL:
 				return Register(data.RegisterD.ToUint32()), []Register{Register(data.RegisterS.ToUint32())}, []Register{Register(data.RegisterS.ToUint32())}
-			case set.OP_LLI, set.OP_LUI:
+			case set.OP_SC:
+				return Register(data.RegisterD.ToUint32()), []Register{Register(data.RegisterS.ToUint32()), Register(data.RegisterD.ToUint32())}, []Register{Register(data.RegisterS.ToUint32())}
+			case set.OP_LLI, set.OP_LUI, set.OP_RDCYCLE, set.OP_RDINSTRET, set.OP_RDPMC:
 				return Register(data.RegisterD.ToUint32()), []Register{}, []Register{}
-			case set.OP_SW:
+			case set.OP_SW, set.OP_SD:
 				return Register(INVALID_INDEX), []Register{Register(data.RegisterS.ToUint32()), Register(data.RegisterD.ToUint32())}, []Register{Register(data.RegisterD.ToUint32())}
 			case set.OP_SLI, set.OP_SUI:
 				return Register(INVALID_INDEX), []Register{Register(data.RegisterD.ToUint32())}, []Register{Register(data.RegisterD.ToUint32())}
@@ -0,0 +1,57 @@
+package staticpredictor
+
+import (
+	"app/simulator/processor/components/pipeline/executor/branch"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/config"
+	"app/simulator/processor/models/instruction"
+	"app/simulator/processor/models/set"
+)
+
+func init() {
+	predictor.Register(config.AlwaysTakenPredictor, newFactory(config.AlwaysTakenPredictor))
+	predictor.Register(config.NeverTakenPredictor, newFactory(config.NeverTakenPredictor))
+	predictor.Register(config.BackwardTakenPredictor, newFactory(config.BackwardTakenPredictor))
+	predictor.Register(config.ForwardTakenPredictor, newFactory(config.ForwardTakenPredictor))
+	predictor.Register(config.HintedPredictor, newFactory(config.HintedPredictor))
+}
+
+// Static predictors only look at the direction of the branch offset or its hint bits, they are never trained
+type StaticPredictor struct {
+	predictorType config.PredictorType
+}
+
+func newFactory(predictorType config.PredictorType) predictor.Factory {
+	return func(*config.Config) (predictor.Predictor, error) {
+		return &StaticPredictor{predictorType: predictorType}, nil
+	}
+}
+
+func (this *StaticPredictor) PredictorType() config.PredictorType {
+	return this.predictorType
+}
+
+func (this *StaticPredictor) Predict(pc uint32, instruction *instruction.Instruction) bool {
+	offset := branch.ComputeOffsetTypeI(instruction.Data, 1)
+	switch this.PredictorType() {
+	case config.AlwaysTakenPredictor:
+		return true
+	case config.BackwardTakenPredictor:
+		return offset < 0
+	case config.ForwardTakenPredictor:
+		return offset > 0
+	case config.HintedPredictor:
+		// Branches without hint bits are predicted backward taken
+		switch set.GetBranchHint(instruction.Info.Opcode) {
+		case set.LikelyHint:
+			return true
+		case set.UnlikelyHint:
+			return false
+		}
+		return offset < 0
+	}
+	return false
+}
+
+func (this *StaticPredictor) Update(pc uint32, taken bool, target uint32, predicted bool) {
+}
@@ -5,12 +5,23 @@ import (
 )
 
 type StorageBus struct {
-	LoadRegister  func(*operation.Operation, uint32) uint32
-	StoreRegister func(*operation.Operation, uint32, uint32)
+	LoadRegister  func(*operation.Operation, uint32) uint64
+	StoreRegister func(*operation.Operation, uint32, uint64)
 
-	LoadData  func(*operation.Operation, uint32) uint32
-	StoreData func(*operation.Operation, uint32, uint32)
+	// Data addresses are as wide as the datapath
+	LoadData  func(*operation.Operation, uint64, uint32) uint64
+	StoreData func(*operation.Operation, uint64, uint32, uint64)
+
+	LoadLinked       func(*operation.Operation, uint64, uint32) uint64
+	StoreConditional func(*operation.Operation, uint64, uint32, uint64) bool
+	StoreAtomic      func(*operation.Operation, uint64, uint32, uint64)
+
+	ReadCounter func(*operation.Operation, uint32) uint32
 
 	IncrementProgramCounter func(*operation.Operation, int32)
 	SetProgramCounter       func(*operation.Operation, uint32)
+	SetupLoop               func(*operation.Operation, uint32, uint32)
+
+	Complete func(*operation.Operation)
+	IsHead   func(*operation.Operation) bool
 }
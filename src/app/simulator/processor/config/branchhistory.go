@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+)
+
+type ChooserIndexType string
+
+const (
+	AddressChooserIndex ChooserIndexType = "address"
+	HistoryChooserIndex ChooserIndexType = "history"
+)
+
+// History of the correlating predictors: global (gshare, gselect) or local per branch address (PAg, PAp)
+// The tournament predictor uses both, with a chooser table indexed by the branch address or the global history
+type BranchHistoryConfig struct {
+	HistoryLength       uint32           `json:"history_length"`
+	PatternTableEntries uint32           `json:"pattern_table_entries"`
+	LocalHistoryEntries uint32           `json:"local_history_entries"`
+	ChooserEntries      uint32           `json:"chooser_entries"`
+	ChooserIndex        ChooserIndexType `json:"chooser_index"`
+}
+
+func (this *BranchHistoryConfig) ChooserIndexType() ChooserIndexType {
+	if this.ChooserIndex == "" {
+		return AddressChooserIndex
+	}
+	return this.ChooserIndex
+}
+
+func (this *BranchHistoryConfig) ToString() string {
+	str := fmt.Sprintf("%d history bits, %d pattern table entries", this.HistoryLength, this.PatternTableEntries)
+	if this.LocalHistoryEntries > 0 {
+		str += fmt.Sprintf(", %d local history entries", this.LocalHistoryEntries)
+	}
+	if this.ChooserEntries > 0 {
+		str += fmt.Sprintf(", %d chooser entries indexed by %s", this.ChooserEntries, this.ChooserIndexType())
+	}
+	return str
+}
+
+// TAGE: a bimodal base table and tagged tables indexed with geometric history lengths from min to max
+type TageConfig struct {
+	BaseEntries  uint32 `json:"base_entries"`
+	Tables       uint32 `json:"tables"`
+	TableEntries uint32 `json:"table_entries"`
+	TagBits      uint32 `json:"tag_bits"`
+	MinHistory   uint32 `json:"min_history"`
+	MaxHistory   uint32 `json:"max_history"`
+}
+
+func (this *TageConfig) ToString() string {
+	return fmt.Sprintf("%d base entries, %d tables of %d entries, %d tag bits, history from %d to %d",
+		this.BaseEntries, this.Tables, this.TableEntries, this.TagBits, this.MinHistory, this.MaxHistory)
+}
+
+// Perceptron: a table of weight vectors over the global history, trained while the output is below the threshold
+type PerceptronConfig struct {
+	Entries       uint32 `json:"entries"`
+	HistoryLength uint32 `json:"history_length"`
+	WeightBits    uint32 `json:"weight_bits"`
+	Threshold     uint32 `json:"threshold"`
+}
+
+// Threshold from Jimenez & Lin when it is not set: 1.93 * history length + 14
+func (this *PerceptronConfig) TrainingThreshold() uint32 {
+	if this.Threshold == 0 {
+		return uint32(1.93*float64(this.HistoryLength) + 14)
+	}
+	return this.Threshold
+}
+
+func (this *PerceptronConfig) ToString() string {
+	return fmt.Sprintf("%d entries, %d history bits, %d bits per weight, threshold %d",
+		this.Entries, this.HistoryLength, this.WeightBits, this.TrainingThreshold())
+}
+
+// Finite table of the one_bit and two_bit predictors, indexed by the low bits of the branch address
+// Without tags, branches sharing an index share their counter (aliasing)
+type BranchHistoryTableConfig struct {
+	IndexBits uint32 `json:"index_bits"`
+	TagBits   uint32 `json:"tag_bits"`
+}
+
+func (this *BranchHistoryTableConfig) ToString() string {
+	return fmt.Sprintf("%d entries, %d tag bits", 1<<this.IndexBits, this.TagBits)
+}
+
+// Loop predictor: trip counts of the loop branches, it overrides the base predictor once the same trip count
+// was seen the confidence number of times in a row
+type LoopPredictorConfig struct {
+	Entries    uint32 `json:"entries"`
+	Confidence uint32 `json:"confidence"`
+}
+
+// Two loop exits with the same trip count when it is not set
+func (this *LoopPredictorConfig) ConfidenceThreshold() uint32 {
+	if this.Confidence == 0 {
+		return 2
+	}
+	return this.Confidence
+}
+
+func (this *LoopPredictorConfig) ToString() string {
+	return fmt.Sprintf("%d entries, confidence %d", this.Entries, this.ConfidenceThreshold())
+}
@@ -0,0 +1,16 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Set-associative buffer of the targets of taken branches, a miss redirects the fetch once the target is decoded
+type BranchTargetBufferConfig struct {
+	Entries       uint32 `json:"entries"`
+	Associativity uint32 `json:"associativity"`
+	MissPenalty   uint32 `json:"miss_penalty"`
+}
+
+func (this *BranchTargetBufferConfig) ToString() string {
+	return fmt.Sprintf("%d entries, %d-way, %d cycles miss penalty", this.Entries, this.Associativity, this.MissPenalty)
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+)
+
+type ReplacementPolicy string
+
+const (
+	LRUReplacement    ReplacementPolicy = "lru"
+	FIFOReplacement   ReplacementPolicy = "fifo"
+	RandomReplacement ReplacementPolicy = "random"
+)
+
+type WritePolicy string
+
+const (
+	WriteBack    WritePolicy = "write_back"
+	WriteThrough WritePolicy = "write_through"
+)
+
+type CacheConfig struct {
+	Size              uint32            `json:"size"`
+	LineSize          uint32            `json:"line_size"`
+	Associativity     uint32            `json:"associativity"`
+	ReplacementPolicy ReplacementPolicy `json:"replacement_policy"`
+	WritePolicy       WritePolicy       `json:"write_policy"`
+	WriteAllocate     bool              `json:"write_allocate"`
+	HitLatency        uint32            `json:"hit_latency"`
+	MissLatency       uint32            `json:"miss_latency"`
+	Bandwidth         uint32            `json:"bandwidth"`
+}
+
+type LevelType string
+
+const (
+	InstructionLevel LevelType = "instruction"
+	DataLevel        LevelType = "data"
+	UnifiedLevel     LevelType = "unified"
+	DRAMLevel        LevelType = "dram"
+)
+
+type DRAMConfig struct {
+	Banks              uint32 `json:"banks"`
+	RowSize            uint32 `json:"row_size"`
+	RowHitLatency      uint32 `json:"row_hit_latency"`
+	RowMissLatency     uint32 `json:"row_miss_latency"`
+	RowConflictLatency uint32 `json:"row_conflict_latency"`
+}
+
+type LevelConfig struct {
+	Name string    `json:"name"`
+	Type LevelType `json:"type"`
+	CacheConfig
+	DRAMConfig
+}
+
+func (this *CacheConfig) Sets() uint32 {
+	return this.Size / (this.LineSize * this.Associativity)
+}
+
+func (this *CacheConfig) ToString() string {
+	str := fmt.Sprintf("%d Bytes, %d Bytes per line, %d-way, %s", this.Size, this.LineSize, this.Associativity, this.ReplacementPolicy)
+	// Read-only caches do not have a write policy
+	if this.WritePolicy != "" {
+		str += fmt.Sprintf(", %s (allocate: %v)", this.WritePolicy, this.WriteAllocate)
+	}
+	str += fmt.Sprintf(", %d/%d cycles (hit/miss)", this.HitLatency, this.MissLatency)
+	if this.Bandwidth > 0 {
+		str += fmt.Sprintf(", %d Bytes per cycle", this.Bandwidth)
+	}
+	return str
+}
+
+func (this *LevelConfig) ToString() string {
+	if this.Type != DRAMLevel {
+		return this.CacheConfig.ToString()
+	}
+	str := fmt.Sprintf("%d banks, %d Bytes per row, %d/%d/%d cycles (row hit/miss/conflict)",
+		this.Banks, this.RowSize, this.RowHitLatency, this.RowMissLatency, this.RowConflictLatency)
+	if this.Bandwidth > 0 {
+		str += fmt.Sprintf(", %d Bytes per cycle", this.Bandwidth)
+	}
+	return str
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"time"
 
 	"app/simulator/processor/consts"
@@ -14,14 +15,38 @@ type Config struct {
 }
 
 type config struct {
-	CyclePeriodMs uint32 `json:"cycle_period_ms"`
+	CyclePeriodMs    uint32     `json:"cycle_period_ms"`
+	ArchitectureSize uint32     `json:"architecture_size"`
+	Endianness       Endianness `json:"endianness"`
 
 	RegistersMemorySize    uint32 `json:"registers_memory_size"`
 	InstructionsMemorySize uint32 `json:"instructions_memory_size"`
 	DataMemorySize         uint32 `json:"data_memory_size"`
 
-	Pipelined           bool          `json:"pipelined"`
-	BranchPredictorType PredictorType `json:"branch_predictor_type"`
+	UnifiedMemory *UnifiedMemoryConfig `json:"unified_memory"`
+
+	CompressedInstructions bool `json:"compressed_instructions"`
+
+	DataCache        *CacheConfig       `json:"data_cache"`
+	InstructionCache *CacheConfig       `json:"instruction_cache"`
+	MemoryHierarchy  []*LevelConfig     `json:"memory_hierarchy"`
+	DataPrefetcher   *PrefetcherConfig  `json:"data_prefetcher"`
+	MemoryPorts      *MemoryPortsConfig `json:"memory_ports"`
+
+	MemoryRegions []*MemoryRegion      `json:"memory_regions"`
+	Devices       []*DeviceConfig      `json:"devices"`
+	VirtualMemory *VirtualMemoryConfig `json:"virtual_memory"`
+
+	Pipelined           bool                      `json:"pipelined"`
+	BranchPredictorType PredictorType             `json:"branch_predictor_type"`
+	BranchHistoryTable  *BranchHistoryTableConfig `json:"branch_history_table"`
+	BranchTargetBuffer  *BranchTargetBufferConfig `json:"branch_target_buffer"`
+	BranchHistory       *BranchHistoryConfig      `json:"branch_history"`
+	Tage                *TageConfig               `json:"tage"`
+	Perceptron          *PerceptronConfig         `json:"perceptron"`
+	LoopPredictor       *LoopPredictorConfig      `json:"loop_predictor"`
+	BranchPredictorArgs json.RawMessage           `json:"branch_predictor_args"`
+	HardwareLoopDepth   uint32                    `json:"hardware_loop_depth"`
 
 	InstructionsFetchedPerCycle    uint32 `json:"instructions_fetched_per_cycle"`
 	InstructionsQueue              uint32 `json:"instructions_queue"`
@@ -31,6 +56,8 @@ type config struct {
 	ReservationStationEntries      uint32 `json:"reservation_station_entries"`
 	ReorderBufferEntries           uint32 `json:"reorder_buffer_entries"`
 	RegisterAliasTableEntries      uint32 `json:"register_alias_table_entries"`
+	LoadQueueEntries               uint32 `json:"load_queue_entries"`
+	StoreQueueEntries              uint32 `json:"store_queue_entries"`
 
 	DecoderUnits   uint32 `json:"decoder_units"`
 	BranchUnits    uint32 `json:"branch_units"`
@@ -50,10 +77,37 @@ const (
 	NeverTakenPredictor    PredictorType = "never_taken"
 	BackwardTakenPredictor PredictorType = "backward_taken"
 	ForwardTakenPredictor  PredictorType = "forward_taken"
+	HintedPredictor        PredictorType = "hinted"
 
 	// Dynamic predictors
 	OneBitPredictor PredictorType = "one_bit"
 	TwoBitPredictor PredictorType = "two_bit"
+
+	// Correlating predictors (2-bit counters indexed by the branch history)
+	GsharePredictor   PredictorType = "gshare"
+	GselectPredictor  PredictorType = "gselect"
+	LocalPAgPredictor PredictorType = "local_pag"
+	LocalPApPredictor PredictorType = "local_pap"
+
+	// Hybrid predictors (a chooser between a local and a global predictor)
+	TournamentPredictor PredictorType = "tournament"
+
+	// Long global history predictors
+	TagePredictor       PredictorType = "tage"
+	PerceptronPredictor PredictorType = "perceptron"
+)
+
+func (this PredictorType) IsLocalHistory() bool {
+	return this == LocalPAgPredictor || this == LocalPApPredictor
+}
+
+// Byte order of every multi-byte value in memory: instructions, data and the hex file
+// When it is not set instructions are laid out big-endian and data little-endian, as before it could be configured
+type Endianness string
+
+const (
+	BigEndian    Endianness = "big"
+	LittleEndian Endianness = "little"
 )
 
 func Load(filename string) (*Config, error) {
@@ -63,11 +117,18 @@ func Load(filename string) (*Config, error) {
 		return nil, err
 	}
 
-	var c *Config
-	if err := json.Unmarshal(bytes, &c); err != nil {
+	// Decoded into the embedded struct, newer encoding/json can not allocate an unexported embedded pointer
+	c := &Config{&config{}}
+	if err := json.Unmarshal(bytes, c.config); err != nil {
 		return nil, err
 	}
 
+	// Files named in the config are relative to it, not to the working directory
+	for _, device := range c.Devices() {
+		if device.InputFile != "" && !filepath.IsAbs(device.InputFile) {
+			device.InputFile = filepath.Join(filepath.Dir(filename), device.InputFile)
+		}
+	}
 	return c, nil
 }
 
@@ -79,12 +140,130 @@ func (this *Config) CyclePeriod() time.Duration {
 	return time.Duration(this.config.CyclePeriodMs) * time.Millisecond
 }
 
+func (this *Config) ArchitectureSize() uint32 {
+	// 32 bits architecture by default, 64 bits datapath if configured
+	if this.config.ArchitectureSize == 0 {
+		return consts.ARCHITECTURE_SIZE
+	}
+	return this.config.ArchitectureSize
+}
+
+func (this *Config) BytesPerWord() uint32 {
+	return this.ArchitectureSize() / consts.BITS_PER_BYTE
+}
+
+// Empty when it is not set
+func (this *Config) Endianness() Endianness {
+	return this.config.Endianness
+}
+
+func (this *Config) InstructionsEndianness() Endianness {
+	if this.config.Endianness == "" {
+		return BigEndian
+	}
+	return this.config.Endianness
+}
+
+// Byte order of the data and register memories
+func (this *Config) DataEndianness() Endianness {
+	if this.config.Endianness == "" {
+		return LittleEndian
+	}
+	return this.config.Endianness
+}
+
+func (this *Config) CompressedInstructions() bool {
+	return this.config.CompressedInstructions
+}
+
+func (this *Config) InstructionAlignment() uint32 {
+	// Compressed instructions are aligned to half words, so are the branch offsets
+	if this.CompressedInstructions() {
+		return consts.BYTES_PER_HALFWORD
+	}
+	return consts.BYTES_PER_WORD
+}
+
+func (this *Config) DataCache() *CacheConfig {
+	return this.getMemoryLevelCache(DataLevel)
+}
+
+func (this *Config) InstructionCache() *CacheConfig {
+	return this.getMemoryLevelCache(InstructionLevel)
+}
+
+// Levels of the memory hierarchy from the top (L1) to the bottom (DRAM)
+func (this *Config) MemoryLevels() []*LevelConfig {
+	if len(this.config.MemoryHierarchy) > 0 {
+		return this.config.MemoryHierarchy
+	}
+	// Stand-alone L1 caches without any lower level
+	levels := []*LevelConfig{}
+	if this.config.InstructionCache != nil && this.config.InstructionCache.Size > 0 {
+		levels = append(levels, &LevelConfig{Name: "L1I", Type: InstructionLevel, CacheConfig: *this.config.InstructionCache})
+	}
+	if this.config.DataCache != nil && this.config.DataCache.Size > 0 {
+		levels = append(levels, &LevelConfig{Name: "L1D", Type: DataLevel, CacheConfig: *this.config.DataCache})
+	}
+	return levels
+}
+
+func (this *Config) getMemoryLevelCache(levelType LevelType) *CacheConfig {
+	// No cache unless one is configured
+	for _, level := range this.MemoryLevels() {
+		if level.Type == levelType {
+			return &level.CacheConfig
+		}
+	}
+	return nil
+}
+
+func (this *Config) DataPrefetcher() *PrefetcherConfig {
+	return this.config.DataPrefetcher
+}
+
+func (this *Config) UnifiedMemory() *UnifiedMemoryConfig {
+	return this.config.UnifiedMemory
+}
+
+// Address where the code is assembled and loaded
+func (this *Config) CodeBase() uint32 {
+	if this.UnifiedMemory() == nil {
+		return 0
+	}
+	return this.UnifiedMemory().CodeBase
+}
+
+// Offset added to the addresses of the memory macros
+func (this *Config) DataBase() uint32 {
+	if this.UnifiedMemory() == nil {
+		return 0
+	}
+	return this.UnifiedMemory().DataBase
+}
+
+func (this *Config) MemoryPorts() *MemoryPortsConfig {
+	return this.config.MemoryPorts
+}
+
+func (this *Config) MemoryRegions() []*MemoryRegion {
+	return this.config.MemoryRegions
+}
+
+func (this *Config) Devices() []*DeviceConfig {
+	return this.config.Devices
+}
+
+func (this *Config) VirtualMemory() *VirtualMemoryConfig {
+	return this.config.VirtualMemory
+}
+
 func (this *Config) RegistersMemorySize() uint32 {
 	return this.config.RegistersMemorySize
 }
 
 func (this *Config) TotalRegisters() uint32 {
-	return this.RegistersMemorySize() / consts.BYTES_PER_WORD
+	return this.RegistersMemorySize() / this.BytesPerWord()
 }
 
 func (this *Config) InstructionsMemorySize() uint32 {
@@ -103,6 +282,39 @@ func (this *Config) BranchPredictorType() PredictorType {
 	return this.config.BranchPredictorType
 }
 
+// Raw parameters of a predictor registered outside this package, decoded by its own factory
+func (this *Config) BranchPredictorArgs() json.RawMessage {
+	return this.config.BranchPredictorArgs
+}
+
+func (this *Config) BranchHistoryTable() *BranchHistoryTableConfig {
+	return this.config.BranchHistoryTable
+}
+
+func (this *Config) BranchTargetBuffer() *BranchTargetBufferConfig {
+	return this.config.BranchTargetBuffer
+}
+
+func (this *Config) BranchHistory() *BranchHistoryConfig {
+	return this.config.BranchHistory
+}
+
+func (this *Config) Tage() *TageConfig {
+	return this.config.Tage
+}
+
+func (this *Config) Perceptron() *PerceptronConfig {
+	return this.config.Perceptron
+}
+
+func (this *Config) LoopPredictor() *LoopPredictorConfig {
+	return this.config.LoopPredictor
+}
+
+func (this *Config) HardwareLoopDepth() uint32 {
+	return this.config.HardwareLoopDepth
+}
+
 func (this *Config) InstructionsFetchedPerCycle() uint32 {
 	return this.config.InstructionsFetchedPerCycle
 }
@@ -135,6 +347,19 @@ func (this *Config) RegisterAliasTableEntries() uint32 {
 	return this.config.RegisterAliasTableEntries
 }
 
+func (this *Config) LoadQueueEntries() uint32 {
+	return this.config.LoadQueueEntries
+}
+
+func (this *Config) StoreQueueEntries() uint32 {
+	return this.config.StoreQueueEntries
+}
+
+// Memory ordering is handled by the load/store queue only if both queues are sized
+func (this *Config) LoadStoreQueue() bool {
+	return this.LoadQueueEntries() > 0 && this.StoreQueueEntries() > 0
+}
+
 func (this *Config) DecoderUnits() uint32 {
 	return this.config.DecoderUnits
 }
@@ -158,12 +383,62 @@ func (this *Config) BranchUnits() uint32 {
 func (this *Config) ToString() string {
 	str := "\n Processor Config:\n\n"
 	str += fmt.Sprintf(" => Cycle Period: %d ms\n", this.CyclePeriodMs())
-	str += fmt.Sprintf(" => Bytes per word: %d\n", consts.BYTES_PER_WORD)
+	str += fmt.Sprintf(" => Architecture: %d bits\n", this.ArchitectureSize())
+	str += fmt.Sprintf(" => Endianness: %s instructions, %s data\n", this.InstructionsEndianness(), this.DataEndianness())
+	str += fmt.Sprintf(" => Bytes per word: %d\n", this.BytesPerWord())
 	str += fmt.Sprintf(" => Registers: %d\n", this.TotalRegisters())
-	str += fmt.Sprintf(" => Instr Memory: %d Bytes\n", this.InstructionsMemorySize())
-	str += fmt.Sprintf(" => Data Memory: %d Bytes\n", this.DataMemorySize())
+	if this.UnifiedMemory() != nil {
+		str += fmt.Sprintf(" => Unified Memory: %s\n", this.UnifiedMemory().ToString())
+	} else {
+		str += fmt.Sprintf(" => Instr Memory: %d Bytes\n", this.InstructionsMemorySize())
+		str += fmt.Sprintf(" => Data Memory: %d Bytes\n", this.DataMemorySize())
+	}
+	str += fmt.Sprintf(" => Compressed Instructions: %v\n", this.CompressedInstructions())
+	for _, region := range this.MemoryRegions() {
+		str += fmt.Sprintf(" => Memory Region %s: %s\n", region.Name, region.ToString())
+	}
+	for _, device := range this.Devices() {
+		str += fmt.Sprintf(" => Device %s: %s\n", device.Name, device.ToString())
+	}
+	if this.VirtualMemory() != nil {
+		str += fmt.Sprintf(" => Virtual Memory: %s\n", this.VirtualMemory().ToString())
+		if this.VirtualMemory().InstructionTLB != nil {
+			str += fmt.Sprintf(" => Instruction TLB: %s\n", this.VirtualMemory().InstructionTLB.ToString())
+		}
+		if this.VirtualMemory().DataTLB != nil {
+			str += fmt.Sprintf(" => Data TLB: %s\n", this.VirtualMemory().DataTLB.ToString())
+		}
+	}
+	for _, level := range this.MemoryLevels() {
+		str += fmt.Sprintf(" => Memory Level %s (%s): %s\n", level.Name, level.Type, level.ToString())
+	}
+	if this.DataPrefetcher() != nil {
+		str += fmt.Sprintf(" => Data Prefetcher: %s\n", this.DataPrefetcher().ToString())
+	}
+	if this.MemoryPorts() != nil {
+		str += fmt.Sprintf(" => Memory Ports: %s\n", this.MemoryPorts().ToString())
+	}
 	str += fmt.Sprintf(" => Pipelined: %v\n", this.Pipelined())
 	str += fmt.Sprintf(" => Branch Predictor Type: %v\n", this.BranchPredictorType())
+	if this.BranchHistoryTable() != nil {
+		str += fmt.Sprintf(" => Branch History Table: %s\n", this.BranchHistoryTable().ToString())
+	}
+	if this.BranchTargetBuffer() != nil {
+		str += fmt.Sprintf(" => Branch Target Buffer: %s\n", this.BranchTargetBuffer().ToString())
+	}
+	if this.BranchHistory() != nil {
+		str += fmt.Sprintf(" => Branch History: %s\n", this.BranchHistory().ToString())
+	}
+	if this.Tage() != nil {
+		str += fmt.Sprintf(" => TAGE: %s\n", this.Tage().ToString())
+	}
+	if this.Perceptron() != nil {
+		str += fmt.Sprintf(" => Perceptron: %s\n", this.Perceptron().ToString())
+	}
+	if this.LoopPredictor() != nil {
+		str += fmt.Sprintf(" => Loop Predictor: %s\n", this.LoopPredictor().ToString())
+	}
+	str += fmt.Sprintf(" => Hardware Loop Depth: %d\n", this.HardwareLoopDepth())
 	str += fmt.Sprintf(" => Instructions Fetched per Cycle: %d\n", this.InstructionsFetchedPerCycle())
 	str += fmt.Sprintf(" => Instructions Queue (IQ): %d\n", this.InstructionsQueue())
 	str += fmt.Sprintf(" => Instructions Decoded Queue (IDQ): %d\n", this.InstructionsDecodedQueue())
@@ -172,6 +447,10 @@ func (this *Config) ToString() string {
 	str += fmt.Sprintf(" => Reservation Station Entries: %d\n", this.ReservationStationEntries())
 	str += fmt.Sprintf(" => Re-order Buffer Entries: %d\n", this.ReorderBufferEntries())
 	str += fmt.Sprintf(" => Register Alias Table Entries: %d\n", this.RegisterAliasTableEntries())
+	if this.LoadStoreQueue() {
+		str += fmt.Sprintf(" => Load Queue Entries: %d\n", this.LoadQueueEntries())
+		str += fmt.Sprintf(" => Store Queue Entries: %d\n", this.StoreQueueEntries())
+	}
 	str += fmt.Sprintf(" => Decoder Units: %d\n", this.DecoderUnits())
 	str += fmt.Sprintf(" => Alu Units: %d\n", this.AluUnits())
 	str += fmt.Sprintf(" => FPU Units: %d\n", this.FpuUnits())
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+)
+
+type DeviceType string
+
+const (
+	ConsoleDevice DeviceType = "console"
+	TimerDevice   DeviceType = "timer"
+	RandomDevice  DeviceType = "random"
+)
+
+// Memory-mapped device, accesses to its address range bypass the data memory and caches
+type DeviceConfig struct {
+	Name      string     `json:"name"`
+	Type      DeviceType `json:"type"`
+	Start     uint32     `json:"start"`
+	Size      uint32     `json:"size"`
+	Latency   uint32     `json:"latency"`
+	InputFile string     `json:"input_file"`
+	Seed      int64      `json:"seed"`
+}
+
+func (this *DeviceConfig) ToString() string {
+	str := fmt.Sprintf("[%#04X, %#04X) %s, %d cycles", this.Start, this.Start+this.Size, this.Type, this.Latency)
+	switch this.Type {
+	case ConsoleDevice:
+		if this.InputFile != "" {
+			str += fmt.Sprintf(", input: %s", this.InputFile)
+		}
+	case RandomDevice:
+		str += fmt.Sprintf(", seed: %d", this.Seed)
+	}
+	return str
+}
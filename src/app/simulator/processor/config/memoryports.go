@@ -0,0 +1,16 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Ports of the data memory shared by the load/store units, banks are interleaved by word
+type MemoryPortsConfig struct {
+	ReadPorts  uint32 `json:"read_ports"`
+	WritePorts uint32 `json:"write_ports"`
+	Banks      uint32 `json:"banks"`
+}
+
+func (this *MemoryPortsConfig) ToString() string {
+	return fmt.Sprintf("%d read ports, %d write ports, %d banks", this.ReadPorts, this.WritePorts, this.Banks)
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+)
+
+type PrefetcherType string
+
+const (
+	NextLinePrefetcher PrefetcherType = "next_line"
+	StridePrefetcher   PrefetcherType = "stride"
+	StreamPrefetcher   PrefetcherType = "stream"
+)
+
+// Data prefetcher in front of the L1 data cache, degree lines are requested distance lines (or strides) ahead
+type PrefetcherConfig struct {
+	Type         PrefetcherType `json:"type"`
+	Degree       uint32         `json:"degree"`
+	Distance     uint32         `json:"distance"`
+	TableEntries uint32         `json:"table_entries"`
+}
+
+func (this *PrefetcherConfig) ToString() string {
+	str := fmt.Sprintf("%s, degree %d, distance %d", this.Type, this.Degree, this.Distance)
+	if this.Type != NextLinePrefetcher {
+		str += fmt.Sprintf(", %d table entries", this.TableEntries)
+	}
+	return str
+}
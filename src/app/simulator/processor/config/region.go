@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+)
+
+type RegionAccess string
+
+const (
+	ReadOnlyRegion  RegionAccess = "read_only"
+	ReadWriteRegion RegionAccess = "read_write"
+	NoAccessRegion  RegionAccess = "no_access"
+)
+
+// Protected region of the data memory, addresses out of any region are read-write
+type MemoryRegion struct {
+	Name   string       `json:"name"`
+	Start  uint32       `json:"start"`
+	Size   uint32       `json:"size"`
+	Access RegionAccess `json:"access"`
+}
+
+func (this *MemoryRegion) ToString() string {
+	return fmt.Sprintf("[%#04X, %#04X) %s", this.Start, this.Start+this.Size, this.Access)
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Single address space for instructions and data, the code is loaded at its base and the memory macros at the data base
+// Stores into the code region flush the younger instructions, it covers the code loaded unless a larger size is set
+type UnifiedMemoryConfig struct {
+	Size     uint32 `json:"size"`
+	CodeBase uint32 `json:"code_base"`
+	CodeSize uint32 `json:"code_size"`
+	DataBase uint32 `json:"data_base"`
+}
+
+func (this *UnifiedMemoryConfig) ToString() string {
+	str := fmt.Sprintf("%d Bytes, code at %#04X, data at %#04X", this.Size, this.CodeBase, this.DataBase)
+	if this.CodeSize > 0 {
+		str += fmt.Sprintf(", code region of %d Bytes", this.CodeSize)
+	}
+	return str
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+)
+
+type TLBConfig struct {
+	Entries       uint32 `json:"entries"`
+	Associativity uint32 `json:"associativity"`
+	MissPenalty   uint32 `json:"miss_penalty"`
+}
+
+// Single-level page table in the data memory, one word per virtual page starting at the base register
+type VirtualMemoryConfig struct {
+	PageSize       uint32     `json:"page_size"`
+	PageTableBase  uint32     `json:"page_table_base"`
+	InstructionTLB *TLBConfig `json:"instruction_tlb"`
+	DataTLB        *TLBConfig `json:"data_tlb"`
+}
+
+func (this *TLBConfig) Sets() uint32 {
+	return this.Entries / this.Associativity
+}
+
+func (this *TLBConfig) ToString() string {
+	return fmt.Sprintf("%d entries, %d-way, %d cycles (miss penalty)", this.Entries, this.Associativity, this.MissPenalty)
+}
+
+func (this *VirtualMemoryConfig) ToString() string {
+	return fmt.Sprintf("%d Bytes per page, page table at %#04X", this.PageSize, this.PageTableBase)
+}
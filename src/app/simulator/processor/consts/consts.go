@@ -6,11 +6,12 @@ const (
 	PROGRAM_FINISHED = 1
 	PROGRAM_RUNNING  = 0
 
-	ENDING_BYTE = 0x77
-
 	ARCHITECTURE_SIZE = 32
 	BITS_PER_BYTE     = 8
 	BYTES_PER_WORD    = ARCHITECTURE_SIZE / BITS_PER_BYTE
+	BYTES_PER_DOUBLE  = 2 * BYTES_PER_WORD
+
+	BYTES_PER_HALFWORD = BYTES_PER_WORD / 2
 
 	STATUS_REGISTER = 0
 	FLAG_PARITY     = 2
@@ -18,6 +19,13 @@ const (
 	FLAG_SIGN       = 7
 	FLAG_OVERFLOW   = 11
 
+	PMC_CYCLES          = 0
+	PMC_INSTRUCTIONS    = 1
+	PMC_MISPREDICTIONS  = 2
+	PMC_ROB_FULL_STALLS = 3
+	PMC_LOADS           = 4
+	PMC_STORES          = 5
+
 	FETCH_CYCLES     = 1
 	DECODE_CYCLES    = 1
 	DISPATCH_CYCLES  = 1
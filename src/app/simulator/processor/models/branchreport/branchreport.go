@@ -0,0 +1,186 @@
+package branchreport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Branches listed on the stats as the hardest to predict
+const WORST_BRANCHES = 5
+
+const FILENAME = "branches.csv"
+
+// Outcomes of a static branch over the committed executions
+type Record struct {
+	Address        uint32
+	Source         string
+	Executions     uint32
+	Taken          uint32
+	Mispredictions uint32
+	StallCycles    uint32
+}
+
+func (this *Record) TakenRate() float32 {
+	return float32(this.Taken) / float32(this.Executions)
+}
+
+func (this *Record) MispredictionRate() float32 {
+	return float32(this.Mispredictions) / float32(this.Executions)
+}
+
+type BranchReport struct {
+	*branchReport
+}
+
+type branchReport struct {
+	records map[uint32]*Record
+	lock    sync.Mutex
+}
+
+func New() *BranchReport {
+	return &BranchReport{
+		&branchReport{
+			records: map[uint32]*Record{},
+		},
+	}
+}
+
+// A committed branch, stall cycles go from its resolution to the redirect of a misprediction
+func (this *BranchReport) Log(address uint32, source string, taken bool, mispredicted bool, stallCycles uint32) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	record, exists := this.branchReport.records[address]
+	if !exists {
+		record = &Record{Address: address, Source: source}
+		this.branchReport.records[address] = record
+	}
+	record.Executions += 1
+	if taken {
+		record.Taken += 1
+	}
+	if mispredicted {
+		record.Mispredictions += 1
+		record.StallCycles += stallCycles
+	}
+}
+
+// Records sorted by address
+func (this *BranchReport) Records() []*Record {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	records := []*Record{}
+	for _, record := range this.branchReport.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Address < records[j].Address
+	})
+	return records
+}
+
+// Branches with the most mispredictions first, then the most stall cycles
+func (this *BranchReport) Worst(n int) []*Record {
+	records := []*Record{}
+	for _, record := range this.Records() {
+		if record.Mispredictions > 0 {
+			records = append(records, record)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Mispredictions != records[j].Mispredictions {
+			return records[i].Mispredictions > records[j].Mispredictions
+		}
+		return records[i].StallCycles > records[j].StallCycles
+	})
+	if len(records) > n {
+		records = records[:n]
+	}
+	return records
+}
+
+func (this *BranchReport) StallCycles() uint32 {
+	total := uint32(0)
+	for _, record := range this.Records() {
+		total += record.StallCycles
+	}
+	return total
+}
+
+// One line per static branch, stall share is the part of all the stall cycles of the run (mispredictions included)
+func (this *BranchReport) Encode(totalStallCycles uint32) []byte {
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	writer.Write([]string{"pc", "source", "executions", "taken", "taken_rate", "mispredictions", "misprediction_rate", "stall_cycles", "stall_share"})
+	for _, record := range this.Records() {
+		stallShare := float32(0)
+		if totalStallCycles > 0 {
+			stallShare = float32(record.StallCycles) / float32(totalStallCycles)
+		}
+		writer.Write([]string{
+			fmt.Sprintf("0x%04X", record.Address),
+			record.Source,
+			fmt.Sprintf("%d", record.Executions),
+			fmt.Sprintf("%d", record.Taken),
+			fmt.Sprintf("%.4f", record.TakenRate()),
+			fmt.Sprintf("%d", record.Mispredictions),
+			fmt.Sprintf("%.4f", record.MispredictionRate()),
+			fmt.Sprintf("%d", record.StallCycles),
+			fmt.Sprintf("%.4f", stallShare),
+		})
+	}
+	writer.Flush()
+	return buffer.Bytes()
+}
+
+// Records of a report saved by a previous run, used as a profile of the branches
+func Load(filename string) ([]*Record, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New(fmt.Sprintf("Branch report %s is empty", filename))
+	}
+	columns := map[string]int{}
+	for i, name := range lines[0] {
+		columns[name] = i
+	}
+	for _, name := range []string{"pc", "source", "executions", "taken", "mispredictions", "stall_cycles"} {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.New(fmt.Sprintf("Branch report %s has no %s column", filename, name))
+		}
+	}
+
+	records := []*Record{}
+	for i, line := range lines[1:] {
+		values := []uint64{}
+		for _, name := range []string{"pc", "executions", "taken", "mispredictions", "stall_cycles"} {
+			value, err := strconv.ParseUint(line[columns[name]], 0, 32)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Failed parsing line %d of %s. %s", i+2, filename, err.Error()))
+			}
+			values = append(values, value)
+		}
+		records = append(records, &Record{
+			Address:        uint32(values[0]),
+			Source:         line[columns["source"]],
+			Executions:     uint32(values[1]),
+			Taken:          uint32(values[2]),
+			Mispredictions: uint32(values[3]),
+			StallCycles:    uint32(values[4]),
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,67 @@
+package branchreport
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newReport() *BranchReport {
+	report := New()
+	report.Log(0x0040, "BEQ R1, R2, loop", true, false, 0)
+	report.Log(0x0040, "BEQ R1, R2, loop", true, true, 7)
+	report.Log(0x0040, "BEQ R1, R2, loop", false, true, 5)
+	report.Log(0x0010, "BNE R3, R0, end", false, false, 0)
+	report.Log(0x0080, "BLT R4, R5, next", true, true, 3)
+	report.Log(0x0080, "BLT R4, R5, next", true, true, 3)
+	return report
+}
+
+func TestWorst(t *testing.T) {
+	// Same mispredictions, the most stall cycles first, branches always predicted right are left out
+	worst := newReport().Worst(WORST_BRANCHES)
+	if len(worst) != 2 {
+		t.Fatalf("Worst branches expected %d - Got %d", 2, len(worst))
+	}
+	for i, expected := range []uint32{0x0040, 0x0080} {
+		if worst[i].Address != expected {
+			t.Errorf("Worst branch %d expected %#04X - Got %#04X", i, expected, worst[i].Address)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	// Stall share: 12 of the 36 stall cycles of the run
+	lines := strings.Split(string(newReport().Encode(36)), "\n")
+	expected := "0x0040,\"BEQ R1, R2, loop\",3,2,0.6667,2,0.6667,12,0.3333"
+	if lines[2] != expected {
+		t.Errorf("Line expected %s - Got %s", expected, lines[2])
+	}
+}
+
+// Saved reports are loaded back as branch profiles
+func TestEncodeLoad(t *testing.T) {
+	report := newReport()
+	file, err := ioutil.TempFile("", FILENAME)
+	if err != nil {
+		t.Fatalf("Temporary file failed: %s", err.Error())
+	}
+	defer os.Remove(file.Name())
+	file.Write(report.Encode(36))
+	file.Close()
+
+	records, err := Load(file.Name())
+	if err != nil {
+		t.Fatalf("Load failed: %s", err.Error())
+	}
+	expected := report.Records()
+	if len(records) != len(expected) {
+		t.Fatalf("Records expected %d - Got %d", len(expected), len(records))
+	}
+	for i, record := range records {
+		if *record != *expected[i] {
+			t.Errorf("Record %d expected %+v - Got %+v", i, *expected[i], *record)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package branchtrace
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+const FILENAME = "branches.trace"
+
+// First line of the file, followed by the instructions retired by the whole run
+const HEADER = "BRTRACE"
+
+// A committed conditional branch, with the encoded instruction (big-endian) and the instructions retired before it
+type Record struct {
+	ProgramCounter uint32
+	Taken          bool
+	Target         uint32
+	Instruction    uint32
+	Retired        uint32
+}
+
+type BranchTrace struct {
+	*branchTrace
+}
+
+type branchTrace struct {
+	records      []*Record
+	instructions uint32
+	lock         sync.Mutex
+}
+
+func New() *BranchTrace {
+	return &BranchTrace{
+		&branchTrace{
+			records: []*Record{},
+		},
+	}
+}
+
+func (this *BranchTrace) Records() []*Record {
+	return this.branchTrace.records
+}
+
+func (this *BranchTrace) Instructions() uint32 {
+	return this.branchTrace.instructions
+}
+
+// Branches are traced at commit, so there is no wrong-path record to squash
+func (this *BranchTrace) Add(record Record) {
+	this.branchTrace.lock.Lock()
+	defer this.branchTrace.lock.Unlock()
+	this.branchTrace.records = append(this.branchTrace.records, &record)
+}
+
+// Header with the instructions retired, then a line per branch: hex pc, taken flag, hex target, hex instruction & retired
+func (this *BranchTrace) Encode(instructions uint32) []byte {
+	this.branchTrace.lock.Lock()
+	defer this.branchTrace.lock.Unlock()
+
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("%s %d\n", HEADER, instructions))
+	for _, record := range this.branchTrace.records {
+		taken := 0
+		if record.Taken {
+			taken = 1
+		}
+		buffer.WriteString(fmt.Sprintf("%x %d %x %08x %d\n", record.ProgramCounter, taken, record.Target, record.Instruction, record.Retired))
+	}
+	return buffer.Bytes()
+}
+
+func Load(filename string) (*BranchTrace, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	trace := New()
+	if _, err := fmt.Sscanf(lines[0], HEADER+" %d", &trace.branchTrace.instructions); err != nil {
+		return nil, errors.New(fmt.Sprintf("Expecting a %s header in %s", HEADER, filename))
+	}
+	for i, line := range lines[1:] {
+		record := &Record{}
+		taken := 0
+		_, err := fmt.Sscanf(line, "%x %d %x %x %d", &record.ProgramCounter, &taken, &record.Target, &record.Instruction, &record.Retired)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed parsing line %d of %s: %s. %s", i+2, filename, line, err.Error()))
+		}
+		record.Taken = taken == 1
+		trace.branchTrace.records = append(trace.branchTrace.records, record)
+	}
+	return trace, nil
+}
@@ -0,0 +1,46 @@
+package branchtrace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodeLoad(t *testing.T) {
+	records := []Record{
+		{ProgramCounter: 0x0040, Taken: true, Target: 0x0020, Instruction: 0x1C22FFF8, Retired: 12},
+		{ProgramCounter: 0x0040, Taken: false, Target: 0x0044, Instruction: 0x1C22FFF8, Retired: 19},
+		{ProgramCounter: 0xFFFC, Taken: true, Target: 0x0000, Instruction: 0x00000001, Retired: 0xFFFFFFFF},
+	}
+	trace := New()
+	for _, record := range records {
+		trace.Add(record)
+	}
+
+	dir, err := ioutil.TempDir("", "branchtrace")
+	if err != nil {
+		t.Fatalf("Temporary directory failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, FILENAME)
+	if err := ioutil.WriteFile(filename, trace.Encode(1234), 0644); err != nil {
+		t.Fatalf("Writing %s failed: %s", filename, err.Error())
+	}
+
+	loaded, err := Load(filename)
+	if err != nil {
+		t.Fatalf("Load failed: %s", err.Error())
+	}
+	if loaded.Instructions() != 1234 {
+		t.Errorf("Instructions expected %d - Got %d", 1234, loaded.Instructions())
+	}
+	if len(loaded.Records()) != len(records) {
+		t.Fatalf("Records expected %d - Got %d", len(records), len(loaded.Records()))
+	}
+	for i, record := range loaded.Records() {
+		if *record != records[i] {
+			t.Errorf("Record %d expected %+v - Got %+v", i, records[i], *record)
+		}
+	}
+}
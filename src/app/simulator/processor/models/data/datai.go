@@ -49,8 +49,10 @@ func getDataIFromParts(parts ...uint32) (*DataI, error) {
 		values = []uint32{parts[0], parts[1], parts[2], parts[3]}
 	} else if len(parts) == 3 {
 		values = []uint32{parts[0], parts[1], 0, parts[2]}
+	} else if len(parts) == 2 {
+		values = []uint32{parts[0], parts[1], 0, 0}
 	} else {
-		return nil, errors.New(fmt.Sprintf("Data I expecting 3 or 4 parts and got %d", len(parts)))
+		return nil, errors.New(fmt.Sprintf("Data I expecting 2, 3 or 4 parts and got %d", len(parts)))
 	}
 
 	return &DataI{
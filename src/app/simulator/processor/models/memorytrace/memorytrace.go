@@ -0,0 +1,173 @@
+package memorytrace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type Format string
+
+const (
+	DinFormat    Format = "din"
+	BinaryFormat Format = "binary"
+)
+
+// Dinero labels
+const (
+	DIN_READ  = 0
+	DIN_WRITE = 1
+)
+
+// Binary records are little-endian and fixed-size, after the header
+const (
+	BINARY_HEADER      = "MEMTRACE"
+	BINARY_RECORD_SIZE = 32
+	FLAG_WRITE         = 0x1
+	FLAG_SQUASHED      = 0x2
+)
+
+type Record struct {
+	Cycle          uint32
+	OperationId    uint32
+	ProgramCounter uint32
+	Address        uint32
+	Size           uint32
+	Write          bool
+	Value          uint64
+	Squashed       bool
+}
+
+type MemoryTrace struct {
+	*memoryTrace
+}
+
+// Records of the operations not committed yet are also indexed by their operation id
+type memoryTrace struct {
+	format  Format
+	records []*Record
+	pending map[uint32][]*Record
+	lock    sync.Mutex
+}
+
+func New(format Format) (*MemoryTrace, error) {
+	if format != DinFormat && format != BinaryFormat {
+		return nil, errors.New(fmt.Sprintf("Unknown memory trace format %s (expecting %s or %s)", format, DinFormat, BinaryFormat))
+	}
+	return &MemoryTrace{
+		&memoryTrace{
+			format:  format,
+			records: []*Record{},
+			pending: map[uint32][]*Record{},
+		},
+	}, nil
+}
+
+func (this *MemoryTrace) Format() Format {
+	return this.memoryTrace.format
+}
+
+func (this *MemoryTrace) Records() []*Record {
+	return this.memoryTrace.records
+}
+
+func (this *MemoryTrace) Filename() string {
+	if this.Format() == BinaryFormat {
+		return "memory.trace"
+	}
+	return "memory.din"
+}
+
+// Accesses are traced when they are executed, so they are speculative until their operation commits
+func (this *MemoryTrace) Add(record Record) {
+	this.memoryTrace.lock.Lock()
+	defer this.memoryTrace.lock.Unlock()
+	this.memoryTrace.records = append(this.memoryTrace.records, &record)
+	this.memoryTrace.pending[record.OperationId] = append(this.memoryTrace.pending[record.OperationId], &record)
+}
+
+// Confirms the accesses of a committed operation, only the last one of a replayed access is kept.
+// Operation ids are reused after a flush, so late accesses of a flushed operation are told apart by their pc
+func (this *MemoryTrace) Commit(operationId, programCounter uint32) {
+	this.memoryTrace.lock.Lock()
+	defer this.memoryTrace.lock.Unlock()
+
+	last := map[bool]*Record{}
+	for _, record := range this.memoryTrace.pending[operationId] {
+		if record.ProgramCounter != programCounter {
+			record.Squashed = true
+			continue
+		}
+		if previous, ok := last[record.Write]; ok {
+			previous.Squashed = true
+		}
+		last[record.Write] = record
+	}
+	delete(this.memoryTrace.pending, operationId)
+}
+
+// Operations are flushed from the given one onwards, their accesses were wrong-path
+func (this *MemoryTrace) Squash(operationId uint32) {
+	this.memoryTrace.lock.Lock()
+	defer this.memoryTrace.lock.Unlock()
+
+	for id, records := range this.memoryTrace.pending {
+		if id < operationId {
+			continue
+		}
+		for _, record := range records {
+			record.Squashed = true
+		}
+		delete(this.memoryTrace.pending, id)
+	}
+}
+
+func (this *MemoryTrace) Encode() []byte {
+	this.memoryTrace.lock.Lock()
+	defer this.memoryTrace.lock.Unlock()
+
+	if this.Format() == BinaryFormat {
+		return this.encodeBinary()
+	}
+	return this.encodeDin()
+}
+
+// Label and hex address first (Dinero), followed by: size, cycle, operation id, pc, value & squashed flag
+func (this *MemoryTrace) encodeDin() []byte {
+	var buffer bytes.Buffer
+	for _, record := range this.memoryTrace.records {
+		label := DIN_READ
+		if record.Write {
+			label = DIN_WRITE
+		}
+		squashed := 0
+		if record.Squashed {
+			squashed = 1
+		}
+		buffer.WriteString(fmt.Sprintf("%d %x %d %d %d %x %x %d\n", label, record.Address, record.Size,
+			record.Cycle, record.OperationId, record.ProgramCounter, record.Value, squashed))
+	}
+	return buffer.Bytes()
+}
+
+// Header and record count, then each record: cycle, operation id, pc, address, size, flags (4 bytes each) & value (8 bytes)
+func (this *MemoryTrace) encodeBinary() []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString(BINARY_HEADER)
+	binary.Write(&buffer, binary.LittleEndian, uint32(len(this.memoryTrace.records)))
+	for _, record := range this.memoryTrace.records {
+		flags := uint32(0)
+		if record.Write {
+			flags |= FLAG_WRITE
+		}
+		if record.Squashed {
+			flags |= FLAG_SQUASHED
+		}
+		binary.Write(&buffer, binary.LittleEndian, []uint32{record.Cycle, record.OperationId,
+			record.ProgramCounter, record.Address, record.Size, flags})
+		binary.Write(&buffer, binary.LittleEndian, record.Value)
+	}
+	return buffer.Bytes()
+}
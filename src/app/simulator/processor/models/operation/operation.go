@@ -1,6 +1,7 @@
 package operation
 
 import (
+	"app/simulator/processor/consts"
 	"app/simulator/processor/models/instruction"
 )
 
@@ -11,11 +12,13 @@ type Operation struct {
 type operation struct {
 	id                  uint32
 	address             uint32
+	size                uint32
 	word                []byte
 	instruction         *instruction.Instruction
 	renamedDestRegister int32
 	predictedAddress    int32
 	taken               bool
+	fault               error
 }
 
 func New(id uint32, address uint32) *Operation {
@@ -23,6 +26,7 @@ func New(id uint32, address uint32) *Operation {
 		&operation{
 			id:                  id,
 			address:             address,
+			size:                consts.BYTES_PER_WORD,
 			predictedAddress:    -1,
 			taken:               false,
 			renamedDestRegister: -1,
@@ -42,6 +46,15 @@ func (this *Operation) Address() uint32 {
 	return this.operation.address
 }
 
+// Bytes taken by the instruction in memory (compressed instructions are expanded into a full word)
+func (this *Operation) Size() uint32 {
+	return this.operation.size
+}
+
+func (this *Operation) NextAddress() uint32 {
+	return this.operation.address + this.operation.size
+}
+
 func (this *Operation) Word() []byte {
 	return this.operation.word
 }
@@ -62,10 +75,23 @@ func (this *Operation) PredictedAddress() int32 {
 	return this.operation.predictedAddress
 }
 
+// Fault raised while executing, delivered when the operation commits
+func (this *Operation) Fault() error {
+	return this.operation.fault
+}
+
+func (this *Operation) SetFault(fault error) {
+	this.operation.fault = fault
+}
+
 func (this *Operation) SetWord(word []byte) {
 	this.operation.word = word
 }
 
+func (this *Operation) SetSize(size uint32) {
+	this.operation.size = size
+}
+
 func (this *Operation) SetInstruction(instruction *instruction.Instruction) {
 	this.operation.instruction = instruction
 }
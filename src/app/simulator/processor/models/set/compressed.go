@@ -0,0 +1,160 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+
+	"app/simulator/processor/consts"
+	"app/simulator/processor/models/data"
+	"app/simulator/processor/models/instruction"
+)
+
+/*
+ |------+-------------------------------------------------------------|
+ | Type | -15-                 Data (bits)                       -0- |
+ |------+--------+--------+--------------------+---------------------|
+ |  CR  | 111(3) | Op (3) |     Rd (5)         |     Rs/C (5)        |
+ |------+--------+--------+---------+----------+---------------------|
+ |  CB  | 111(3) | Op (3) | Rd' (3) | Rs' (3)  |     Offset (4)      |
+ |------+--------+--------+---------+----------+---------------------|
+ |  CJ  | 111(3) | Op (3) |              Offset (10)                 |
+ |------+--------+--------+------------------------------------------|
+
+ - Compressed instructions take the opcodes from 0x38 to 0x3F (the first three bits set), shared with the hinted branches
+   so programs with branch hints cannot be compressed
+ - Rd' and Rs' are the registers from R8 to R15
+ - Offsets are signed and measured in half words from the next instruction
+*/
+
+const (
+	OP_COMPRESSED = 0x38
+
+	C_ADDI = 0x0 // addi Rd, Rd, C (C < 32)
+	C_MOV  = 0x1 // addi Rd, Rs, 0
+	C_ADD  = 0x2 // add  Rd, Rd, Rs
+	C_LW   = 0x3 // lw   Rd, Rs, 0
+	C_SW   = 0x4 // sw   Rd, Rs, 0
+	C_BEQ  = 0x5 // beq  Rd', Rs', offset
+	C_BNE  = 0x6 // bne  Rd', Rs', offset
+	C_J    = 0x7 // j    offset
+
+	compressedRegisterBase = 8
+)
+
+// Compressed instructions are recognized by the first byte (instructions are stored big-endian)
+func IsCompressed(firstByte byte) bool {
+	return (firstByte>>2)&OP_COMPRESSED == OP_COMPRESSED
+}
+
+// Returns the 16 bits encoding of an instruction placed at the given address, if it has one
+func Compress(instruction *instruction.Instruction, address uint32) ([]byte, bool) {
+	nextAddress := address + consts.BYTES_PER_HALFWORD
+
+	switch instruction.Info.Opcode {
+	case OP_ADDI:
+		operands := instruction.Data.(*data.DataI)
+		rd, rs, immediate := operands.RegisterD.ToUint32(), operands.RegisterS.ToUint32(), operands.Immediate.ToUint32()
+		if rd == rs && immediate < 32 {
+			return encodeCR(C_ADDI, rd, immediate), true
+		}
+		if immediate == 0 {
+			return encodeCR(C_MOV, rd, rs), true
+		}
+	case OP_ADD:
+		operands := instruction.Data.(*data.DataR)
+		rd, rs, rt := operands.RegisterD.ToUint32(), operands.RegisterS.ToUint32(), operands.RegisterT.ToUint32()
+		if rd == rs {
+			return encodeCR(C_ADD, rd, rt), true
+		}
+	case OP_LW, OP_SW:
+		operands := instruction.Data.(*data.DataI)
+		if operands.Immediate.ToUint32() == 0 {
+			op := uint32(C_LW)
+			if instruction.Info.Opcode == OP_SW {
+				op = C_SW
+			}
+			return encodeCR(op, operands.RegisterD.ToUint32(), operands.RegisterS.ToUint32()), true
+		}
+	case OP_BEQ, OP_BNE:
+		operands := instruction.Data.(*data.DataI)
+		rd, rs := operands.RegisterD.ToUint32(), operands.RegisterS.ToUint32()
+		offset := signExtend(operands.Immediate.ToUint32(), 16)
+		if isCompressedRegister(rd) && isCompressedRegister(rs) && fitsSigned(offset, 4) {
+			op := uint32(C_BEQ)
+			if instruction.Info.Opcode == OP_BNE {
+				op = C_BNE
+			}
+			value := op<<10 + (rd-compressedRegisterBase)<<7 + (rs-compressedRegisterBase)<<4 + uint32(offset)&0xF
+			return encode(value), true
+		}
+	case OP_J:
+		target := instruction.Data.(*data.DataJ).Address.ToUint32()
+		offset := int32(target) - int32(nextAddress/consts.BYTES_PER_HALFWORD)
+		if fitsSigned(offset, 10) {
+			return encode(C_J<<10 + uint32(offset)&0x3FF), true
+		}
+	}
+	return nil, false
+}
+
+// Returns the 32 bits instruction equivalent to a compressed instruction placed at the given address
+func Expand(bytes []byte, address uint32) ([]byte, error) {
+
+	if len(bytes) != consts.BYTES_PER_HALFWORD {
+		return nil, errors.New(fmt.Sprintf("Expecting %d bytes and got %d", consts.BYTES_PER_HALFWORD, len(bytes)))
+	}
+
+	value := uint32(bytes[0])<<8 + uint32(bytes[1])
+	rd, rs := (value>>5)&0x1F, value&0x1F
+	rdc, rsc := (value>>7)&0x07+compressedRegisterBase, (value>>4)&0x07+compressedRegisterBase
+
+	var datatype data.TypeEnum
+	var parts []uint32
+	switch (value >> 10) & 0x07 {
+	case C_ADDI:
+		datatype, parts = data.TypeI, []uint32{OP_ADDI, rd, rd, rs}
+	case C_MOV:
+		datatype, parts = data.TypeI, []uint32{OP_ADDI, rd, rs, 0}
+	case C_ADD:
+		datatype, parts = data.TypeR, []uint32{OP_ADD, rd, rd, rs}
+	case C_LW:
+		datatype, parts = data.TypeI, []uint32{OP_LW, rd, rs, 0}
+	case C_SW:
+		datatype, parts = data.TypeI, []uint32{OP_SW, rd, rs, 0}
+	case C_BEQ:
+		datatype, parts = data.TypeI, []uint32{OP_BEQ, rdc, rsc, uint32(signExtend(value&0x0F, 4)) & 0xFFFF}
+	case C_BNE:
+		datatype, parts = data.TypeI, []uint32{OP_BNE, rdc, rsc, uint32(signExtend(value&0x0F, 4)) & 0xFFFF}
+	case C_J:
+		target := int32((address+consts.BYTES_PER_HALFWORD)/consts.BYTES_PER_HALFWORD) + signExtend(value&0x3FF, 10)
+		datatype, parts = data.TypeJ, []uint32{OP_J, uint32(target)}
+	}
+
+	expanded, err := data.GetDataFromParts(datatype, parts...)
+	if err != nil {
+		return nil, err
+	}
+	word := expanded.ToUint32()
+	return []byte{byte(word >> 24), byte(word >> 16), byte(word >> 8), byte(word)}, nil
+}
+
+func encodeCR(op uint32, rd uint32, rs uint32) []byte {
+	return encode(op<<10 + rd<<5 + rs)
+}
+
+func encode(value uint32) []byte {
+	value += OP_COMPRESSED << 10
+	return []byte{byte(value >> 8), byte(value)}
+}
+
+func isCompressedRegister(register uint32) bool {
+	return register >= compressedRegisterBase && register < compressedRegisterBase+8
+}
+
+func signExtend(value uint32, bits uint32) int32 {
+	return int32(value<<(32-bits)) >> (32 - bits)
+}
+
+func fitsSigned(value int32, bits uint32) bool {
+	return value >= -(1<<(bits-1)) && value < 1<<(bits-1)
+}
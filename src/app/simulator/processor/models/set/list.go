@@ -1,6 +1,8 @@
 package set
 
 import (
+	"strings"
+
 	"app/simulator/processor/models/data"
 	"app/simulator/processor/models/info"
 )
@@ -26,6 +28,19 @@ const (
 	OP_OR   = 0x0F
 	OP_ORI  = 0x10
 
+	OP_ADDW  = 0x11
+	OP_ADDIW = 0x16
+	OP_SUBW  = 0x17
+	OP_MULW  = 0x1C
+	OP_SHLW  = 0x1D
+	OP_SHRW  = 0x1E
+
+	OP_RDCYCLE   = 0x18
+	OP_RDINSTRET = 0x19
+	OP_RDPMC     = 0x1A
+
+	OP_LOOP = 0x1B
+
 	OP_FADD = 0x12
 	OP_FSUB = 0x13
 	OP_FMUL = 0x14
@@ -38,11 +53,38 @@ const (
 	OP_LUI = 0x24
 	OP_SUI = 0x25
 
+	OP_LL      = 0x26
+	OP_SC      = 0x27
+	OP_CAS     = 0x28
+	OP_AMOADD  = 0x29
+	OP_AMOSWAP = 0x2A
+	OP_FENCE   = 0x2B
+
+	OP_LD = 0x2C
+	OP_SD = 0x2D
+
 	OP_BEQ = 0x30
 	OP_BNE = 0x31
 	OP_BLT = 0x32
 	OP_BGT = 0x33
 	OP_J   = 0x34
+
+	// Ends the program once it is fetched
+	OP_HALT = 0x35
+
+	// Hint bits of the conditional branches: bit 3 of the opcode marks a hint, bit 2 an unlikely one
+	// Hinted opcodes overlap the compressed ones, both cannot be used in the same program
+	OP_HINT_LIKELY   = 0x08
+	OP_HINT_UNLIKELY = 0x0C
+	OP_HINT_MASK     = 0x0C
+)
+
+type BranchHint string
+
+const (
+	NoHint       BranchHint = ""
+	LikelyHint   BranchHint = "likely"
+	UnlikelyHint BranchHint = "unlikely"
 )
 
 func Init() Set {
@@ -67,6 +109,19 @@ func Init() Set {
 		info.New(OP_OR, "or", info.Aritmetic, data.TypeR, 2),
 		info.New(OP_ORI, "ori", info.Aritmetic, data.TypeR, 2),
 
+		info.New(OP_ADDW, "addw", info.Aritmetic, data.TypeR, 2),
+		info.New(OP_ADDIW, "addiw", info.Aritmetic, data.TypeI, 2),
+		info.New(OP_SUBW, "subw", info.Aritmetic, data.TypeR, 2),
+		info.New(OP_MULW, "mulw", info.Aritmetic, data.TypeR, 4),
+		info.New(OP_SHLW, "shlw", info.Aritmetic, data.TypeR, 2),
+		info.New(OP_SHRW, "shrw", info.Aritmetic, data.TypeR, 2),
+
+		info.New(OP_RDCYCLE, "rdcycle", info.Aritmetic, data.TypeI, 1),
+		info.New(OP_RDINSTRET, "rdinstret", info.Aritmetic, data.TypeI, 1),
+		info.New(OP_RDPMC, "rdpmc", info.Aritmetic, data.TypeI, 1),
+
+		info.New(OP_LOOP, "loop", info.Aritmetic, data.TypeI, 1),
+
 		info.New(OP_FADD, "fadd", info.FloatingPoint, data.TypeR, 8),
 		info.New(OP_FSUB, "fsub", info.FloatingPoint, data.TypeR, 8),
 		info.New(OP_FMUL, "fmul", info.FloatingPoint, data.TypeR, 8),
@@ -79,10 +134,122 @@ func Init() Set {
 		info.New(OP_LUI, "lui", info.LoadStore, data.TypeI, 1),
 		info.New(OP_SUI, "sui", info.LoadStore, data.TypeI, 1),
 
+		info.New(OP_LL, "ll", info.LoadStore, data.TypeI, 2),
+		info.New(OP_SC, "sc", info.LoadStore, data.TypeI, 2),
+		info.New(OP_CAS, "cas", info.LoadStore, data.TypeR, 4),
+		info.New(OP_AMOADD, "amoadd", info.LoadStore, data.TypeR, 4),
+		info.New(OP_AMOSWAP, "amoswap", info.LoadStore, data.TypeR, 4),
+		info.New(OP_FENCE, "fence", info.LoadStore, data.TypeJ, 1),
+
+		info.New(OP_LD, "ld", info.LoadStore, data.TypeI, 2),
+		info.New(OP_SD, "sd", info.LoadStore, data.TypeI, 2),
+
 		info.New(OP_BEQ, "beq", info.Control, data.TypeI, 1),
 		info.New(OP_BNE, "bne", info.Control, data.TypeI, 1),
 		info.New(OP_BLT, "blt", info.Control, data.TypeI, 1),
 		info.New(OP_BGT, "bgt", info.Control, data.TypeI, 1),
 		info.New(OP_J, "j", info.Control, data.TypeJ, 1),
+		info.New(OP_HALT, "halt", info.Control, data.TypeJ, 1),
+
+		info.New(OP_BEQ|OP_HINT_LIKELY, "beq.likely", info.Control, data.TypeI, 1),
+		info.New(OP_BNE|OP_HINT_LIKELY, "bne.likely", info.Control, data.TypeI, 1),
+		info.New(OP_BLT|OP_HINT_LIKELY, "blt.likely", info.Control, data.TypeI, 1),
+		info.New(OP_BGT|OP_HINT_LIKELY, "bgt.likely", info.Control, data.TypeI, 1),
+		info.New(OP_BEQ|OP_HINT_UNLIKELY, "beq.unlikely", info.Control, data.TypeI, 1),
+		info.New(OP_BNE|OP_HINT_UNLIKELY, "bne.unlikely", info.Control, data.TypeI, 1),
+		info.New(OP_BLT|OP_HINT_UNLIKELY, "blt.unlikely", info.Control, data.TypeI, 1),
+		info.New(OP_BGT|OP_HINT_UNLIKELY, "bgt.unlikely", info.Control, data.TypeI, 1),
 	}
 }
+
+// Hinted conditional branches are executed as the branch without hint bits
+func GetBranchCondition(opcode uint8) uint8 {
+	if GetBranchHint(opcode) != NoHint {
+		return opcode &^ OP_HINT_MASK
+	}
+	return opcode
+}
+
+func GetBranchHint(opcode uint8) BranchHint {
+	if opcode < OP_BEQ|OP_HINT_LIKELY || opcode > OP_BGT|OP_HINT_UNLIKELY {
+		return NoHint
+	}
+	if opcode&OP_HINT_MASK == OP_HINT_UNLIKELY {
+		return UnlikelyHint
+	}
+	return LikelyHint
+}
+
+// Mnemonic of a conditional branch with the given hint, e.g. blt.likely
+func GetHintedBranchName(name string, hint BranchHint) string {
+	name = strings.Split(name, ".")[0]
+	if hint == NoHint {
+		return name
+	}
+	return name + "." + string(hint)
+}
+
+// Atomic operations (and fences) are executed non-speculatively at the head of the re-order buffer
+func IsAtomic(opcode uint8) bool {
+	switch opcode {
+	case OP_LL, OP_SC, OP_CAS, OP_AMOADD, OP_AMOSWAP, OP_FENCE:
+		return true
+	}
+	return false
+}
+
+// Serializing operations wait until all older operations are committed before being executed
+func IsSerializing(opcode uint8) bool {
+	switch opcode {
+	case OP_RDCYCLE, OP_RDINSTRET, OP_RDPMC:
+		return true
+	}
+	return IsAtomic(opcode)
+}
+
+// Operations reading or writing data memory
+func AccessesMemory(opcode uint8) bool {
+	switch opcode {
+	case OP_LW, OP_SW, OP_SLI, OP_SUI, OP_LD, OP_SD:
+		return true
+	}
+	return IsAtomic(opcode)
+}
+
+// Doubleword accesses, only available on a 64-bit architecture
+func IsDoubleword(opcode uint8) bool {
+	return opcode == OP_LD || opcode == OP_SD
+}
+
+// Operations reading data memory
+func IsLoad(opcode uint8) bool {
+	switch opcode {
+	case OP_LW, OP_LD, OP_LL, OP_CAS, OP_AMOADD, OP_AMOSWAP:
+		return true
+	}
+	return false
+}
+
+// Operations writing data memory
+func IsStore(opcode uint8) bool {
+	switch opcode {
+	case OP_SW, OP_SD, OP_SLI, OP_SUI, OP_SC, OP_CAS, OP_AMOADD, OP_AMOSWAP:
+		return true
+	}
+	return false
+}
+
+// Operations declared without any operand in the assembly
+func HasOperands(opcode uint8) bool {
+	return opcode != OP_FENCE && opcode != OP_HALT
+}
+
+// Encoded halt instruction (big-endian word)
+func HaltWord() uint32 {
+	halt, _ := data.GetDataFromParts(data.TypeJ, OP_HALT, 0)
+	return halt.ToUint32()
+}
+
+func IsHalt(word uint32) bool {
+	return data.GetOpcodeFromUint32(word) == OP_HALT
+}
@@ -33,13 +33,11 @@ func (this Set) GetInstructionInfoFromOpcode(opcode uint8) (*info.Info, error) {
 	return nil, errors.New(fmt.Sprintf("No instruction was found with name: %d", opcode))
 }
 
-func (this Set) GetInstructionFromString(line string, address uint32, labels map[string]uint32) (*instruction.Instruction, error) {
+// Branch offsets are computed from the next instruction address in units of the instruction alignment
+func (this Set) GetInstructionFromString(line string, nextAddress uint32, alignment uint32, labels map[string]uint32) (*instruction.Instruction, error) {
 
 	// Clean line and split by items
-	items, err := getItemsFromString(line)
-	if err != nil {
-		return nil, err
-	}
+	items := getPseudoInstructionItems(getItemsFromString(line))
 
 	// Search opcode in the instruction set
 	opInfo, err := this.GetInstructionInfoFromName(items[0])
@@ -47,6 +45,10 @@ func (this Set) GetInstructionFromString(line string, address uint32, labels map
 		return nil, err
 	}
 
+	if len(items) <= 1 && HasOperands(opInfo.Opcode) {
+		return nil, errors.New(fmt.Sprintf("Only one operand found in the instruction: %s. Expecting more than one operand", line))
+	}
+
 	// Check all operands (except opcode/operation) is a numeric value
 	operands := []uint32{uint32(opInfo.Opcode)}
 	for _, value := range items[1:] {
@@ -56,10 +58,10 @@ func (this Set) GetInstructionFromString(line string, address uint32, labels map
 		labelAddress, isLabel := labels[value]
 		if isLabel {
 			if opInfo.Type == data.TypeJ {
-				offset := computeBranchAddress(labelAddress)
+				offset := computeBranchAddress(labelAddress, alignment)
 				operands = append(operands, offset)
 			} else {
-				offset := computeBranchOffset(labelAddress, address)
+				offset := computeBranchOffset(labelAddress, nextAddress, alignment)
 				operands = append(operands, offset)
 			}
 		} else {
@@ -79,6 +81,11 @@ func (this Set) GetInstructionFromString(line string, address uint32, labels map
 		}
 	}
 
+	// Instructions without operands are encoded with an empty address
+	if !HasOperands(opInfo.Opcode) {
+		operands = append(operands, 0)
+	}
+
 	// Get data object from operands
 	data, err := data.GetDataFromParts(opInfo.Type, operands...)
 	if err != nil {
@@ -112,25 +119,28 @@ func (this Set) GetInstructionFromBytes(bytes []byte) (*instruction.Instruction,
 	return instruction.New(info, data), nil
 }
 
-func getItemsFromString(line string) ([]string, error) {
+func getItemsFromString(line string) []string {
 	line = strings.Replace(line, "\t", " ", -1)
 	line = strings.Replace(line, ",", " ", -1)
 	line = strings.TrimSpace(line)
-	items := strings.Split(line, " ")
+	return strings.Split(line, " ")
+}
 
-	if len(items) <= 1 {
-		return nil, errors.New(fmt.Sprintf("Only one operand found in the instruction: %s. Expecting more than one operand", line))
+// Pseudo-instructions are replaced by an equivalent instruction of the set
+func getPseudoInstructionItems(items []string) []string {
+	if strings.ToLower(items[0]) == "mov" && len(items) == 3 {
+		return []string{"addi", items[1], items[2], "0"}
 	}
-	return items, nil
+	return items
 }
 
-func computeBranchOffset(labelAddress, instructionAddress uint32) uint32 {
-	offsetAddress := labelAddress - instructionAddress - 4
+func computeBranchOffset(labelAddress, nextAddress, alignment uint32) uint32 {
+	offsetAddress := int32(labelAddress-nextAddress) / int32(alignment)
 	// If offset is negative, offset will be already in Two's complement per uint32 variables
 	// See ref https://golang.org/ref/spec: "...represented using two's complement arithmetic"
-	return offsetAddress >> 2
+	return uint32(offsetAddress)
 }
 
-func computeBranchAddress(labelAddress uint32) uint32 {
-	return labelAddress >> 2
+func computeBranchAddress(labelAddress, alignment uint32) uint32 {
+	return labelAddress / alignment
 }
@@ -7,10 +7,20 @@ import (
 	"strings"
 
 	"app/logger"
+	"app/simulator/processor/components/branchhistory"
+	"app/simulator/processor/components/branchtargetbuffer"
+	"app/simulator/processor/components/cache"
 	"app/simulator/processor/components/clock"
+	"app/simulator/processor/components/device"
+	"app/simulator/processor/components/dram"
 	"app/simulator/processor/components/memory"
+	"app/simulator/processor/components/memoryports"
+	"app/simulator/processor/components/mmu"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/components/prefetcher"
 	"app/simulator/processor/config"
 	"app/simulator/processor/consts"
+	"app/simulator/processor/models/branchreport"
 	"app/simulator/processor/models/set"
 	"app/utils"
 )
@@ -24,40 +34,186 @@ func New(assemblyFileName string, config *config.Config) (*Processor, error) {
 			instructionsCompleted: []uint32{},
 			dataLog:               map[uint32][]LogEvent{},
 
-			branchHistoryTable:    map[uint32]uint32{},
 			conditionalBranches:   0,
 			unconditionalBranches: 0,
 			mispredictedBranches:  0,
 			noTakenBranches:       0,
-			branchPredictorBits:   0,
 			speculativeJumps:      0,
+			branchReport:          branchreport.New(),
+
+			hardwareLoops:            []HardwareLoop{},
+			speculativeHardwareLoops: []HardwareLoop{},
 
 			instructionsMap: map[uint32]string{},
 			instructionsSet: set.Init(),
 			config:          config,
 
-			programCounter:    0,
-			registerMemory:    memory.New(config.RegistersMemorySize()),
-			instructionMemory: memory.New(config.InstructionsMemorySize()),
-			dataMemory:        memory.New(config.DataMemorySize()),
+			programCounter:    config.CodeBase(),
+			registerMemory:    memory.New(config.RegistersMemorySize(), config.BytesPerWord()),
+			instructionMemory: memory.New(config.InstructionsMemorySize(), consts.BYTES_PER_WORD),
+			dataMemory:        memory.New(config.DataMemorySize(), config.BytesPerWord()),
 		},
 	}
+	if err := p.checkEndianness(); err != nil {
+		return p, err
+	}
+	if config.UnifiedMemory() != nil {
+		// Instructions and data share a single memory (von Neumann)
+		if config.VirtualMemory() != nil {
+			return p, errors.New("Unified memory can not be combined with virtual memory")
+		}
+		p.processor.dataMemory = memory.New(config.UnifiedMemory().Size, config.BytesPerWord())
+		p.processor.instructionMemory = p.processor.dataMemory
+	}
+	p.processor.instructionMemory.SetEndianness(config.InstructionsEndianness())
+	for _, m := range []*memory.Memory{p.processor.registerMemory, p.processor.dataMemory} {
+		m.SetEndianness(config.DataEndianness())
+	}
+	p.processor.dataMemory.SetRegions(config.MemoryRegions())
+
+	err := p.buildMemoryHierarchy()
+	if err != nil {
+		return p, err
+	}
+
+	devices, err := device.New(config.Devices(), p.Cycles)
+	if err != nil {
+		return p, err
+	}
+	p.processor.devices = devices
+
+	if config.VirtualMemory() != nil {
+		p.processor.mmu, err = mmu.New(config.VirtualMemory(), p.processor.dataMemory, p.processor.dataLevel)
+		if err != nil {
+			return p, err
+		}
+	}
+
+	if config.DataPrefetcher() != nil {
+		if p.processor.dataCache == nil {
+			return p, errors.New("Data prefetcher requires a data cache")
+		}
+		p.processor.dataPrefetcher, err = prefetcher.New(config.DataPrefetcher(), p.processor.dataCache)
+		if err != nil {
+			return p, err
+		}
+	}
+
+	err = p.buildBranchPredictor()
+	if err != nil {
+		return p, err
+	}
+
+	if config.MemoryPorts() != nil {
+		p.processor.memoryPorts, err = memoryports.New(config.MemoryPorts())
+		if err != nil {
+			return p, err
+		}
+	}
 
 	logger.Print(config.ToString())
 
 	// Instanciate functional units
 	instructionsFinished := func() bool {
-		return p.processor.done && p.InstructionsFetchedCounter() == p.InstructionsCompletedCounter() && p.SpeculativeJumps() == 0
+		return p.processor.fault != nil ||
+			(p.processor.done && p.InstructionsFetchedCounter() == p.InstructionsCompletedCounter() && p.SpeculativeJumps() == 0)
 	}
 	p.processor.clockUnit = clock.New(config.CyclePeriod(), instructionsFinished)
 
-	err := p.loadInstructionsMemory(assemblyFileName)
+	err = p.loadInstructionsMemory(assemblyFileName)
 	if err != nil {
 		return p, err
 	}
 	return p, nil
 }
 
+// Predictors are looked up by name on the registry, the stall predictor has none.
+// The loop predictor can override any of them
+func (this *Processor) buildBranchPredictor() error {
+	var err error
+	if this.Config().BranchTargetBuffer() != nil {
+		this.processor.branchTargetBuffer, err = branchtargetbuffer.New(this.Config().BranchTargetBuffer(), this.Config().InstructionAlignment())
+		if err != nil {
+			return err
+		}
+	}
+	if this.Config().BranchPredictorType() == config.StallPredictor {
+		return nil
+	}
+	base, err := predictor.New(this.Config())
+	if err != nil {
+		return err
+	}
+	this.processor.predictor, err = branchhistory.WithLoopPredictor(this.Config(), base)
+	return err
+}
+
+func (this *Processor) checkEndianness() error {
+	endianness := this.Config().Endianness()
+	if endianness != "" && endianness != config.BigEndian && endianness != config.LittleEndian {
+		return errors.New(fmt.Sprintf("Unknown endianness %s (expecting %s or %s)", endianness, config.BigEndian, config.LittleEndian))
+	}
+	// Instructions and data default to different byte orders, a single memory holds only one
+	if this.Config().UnifiedMemory() != nil && endianness == "" {
+		return errors.New(fmt.Sprintf("Unified memory requires an endianness (%s or %s)", config.BigEndian, config.LittleEndian))
+	}
+	// Instruction length is told by its first byte, which only holds the opcode on big-endian
+	if this.Config().CompressedInstructions() && this.Config().InstructionsEndianness() != config.BigEndian {
+		return errors.New("Compressed instructions require big-endian")
+	}
+	return nil
+}
+
+func (this *Processor) buildMemoryHierarchy() error {
+	var err error
+
+	// Shared levels are chained from the bottom (DRAM) to the top
+	var next cache.Level
+	levels := this.Config().MemoryLevels()
+	shared := []cache.Level{}
+	for i := len(levels) - 1; i >= 0; i-- {
+		switch levels[i].Type {
+		case config.DRAMLevel:
+			next, err = dram.New(levels[i].Name, levels[i])
+			if err != nil {
+				return err
+			}
+		case config.UnifiedLevel:
+			next, err = cache.New(levels[i].Name, &levels[i].CacheConfig, next)
+			if err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		shared = append([]cache.Level{next}, shared...)
+	}
+
+	// L1 caches are private to the fetch and load/store units and share the first lower level
+	this.processor.instructionLevel = next
+	this.processor.dataLevel = next
+	for _, level := range levels {
+		switch level.Type {
+		case config.InstructionLevel:
+			this.processor.instructionCache, err = cache.New(level.Name, &level.CacheConfig, next)
+			if err != nil {
+				return err
+			}
+			this.processor.instructionLevel = this.processor.instructionCache
+			this.processor.memoryLevels = append(this.processor.memoryLevels, this.processor.instructionCache)
+		case config.DataLevel:
+			this.processor.dataCache, err = cache.New(level.Name, &level.CacheConfig, next)
+			if err != nil {
+				return err
+			}
+			this.processor.dataLevel = this.processor.dataCache
+			this.processor.memoryLevels = append(this.processor.memoryLevels, this.processor.dataCache)
+		}
+	}
+	this.processor.memoryLevels = append(this.processor.memoryLevels, shared...)
+	return nil
+}
+
 func (this *Processor) loadInstructionsMemory(assemblyFileName string) error {
 
 	logger.Print(" => Reading hex file: %s", assemblyFileName)
@@ -66,7 +222,7 @@ func (this *Processor) loadInstructionsMemory(assemblyFileName string) error {
 		return err
 	}
 
-	address := uint32(0)
+	address := this.Config().CodeBase()
 	for _, line := range lines {
 
 		// Split hex value and humand readable comment
@@ -92,13 +248,46 @@ func (this *Processor) loadInstructionsMemory(assemblyFileName string) error {
 		if err != nil {
 			return errors.New(fmt.Sprintf("Failed parsing instruction (hex) value: %s. %s", parts[0], err.Error()))
 		}
+		if !this.InstructionsMemory().InRange(address, uint32(len(bytes))) {
+			return errors.New(fmt.Sprintf("Instruction at %#04X out of the instructions memory (%d Bytes)", address, this.InstructionsMemory().Size()))
+		}
 		this.InstructionsMemory().Store(address, bytes...)
 
 		// Increment address
 		address += uint32(len(bytes))
 	}
-	for i := address; i < this.InstructionsMemory().Size(); i++ {
-		this.InstructionsMemory().Store(i, []byte{consts.ENDING_BYTE}...)
+	this.processor.codeSize = address - this.Config().CodeBase()
+	return this.storeHalts(address)
+}
+
+// Programs falling off the end of the code stop at a halt, the rest of an instructions memory is filled with them
+// A unified memory holds data after the code, so it only gets one
+func (this *Processor) storeHalts(address uint32) error {
+	halt := memory.Encode(uint64(set.HaltWord()), consts.BYTES_PER_WORD, this.Config().InstructionsEndianness())
+	unified := this.Config().UnifiedMemory()
+	if unified == nil {
+		for ; this.InstructionsMemory().InRange(address, consts.BYTES_PER_WORD); address += consts.BYTES_PER_WORD {
+			this.InstructionsMemory().Store(address, halt...)
+		}
+		return nil
+	}
+
+	codeEnd := address + consts.BYTES_PER_WORD
+	if unified.DataBase > unified.CodeBase && codeEnd > unified.DataBase {
+		return errors.New(fmt.Sprintf("Code and its ending halt [%#04X, %#04X) overlap the data at %#04X", unified.CodeBase, codeEnd, unified.DataBase))
+	}
+	if !this.InstructionsMemory().InRange(address, consts.BYTES_PER_WORD) {
+		return errors.New(fmt.Sprintf("No room for the ending halt at %#04X (%d Bytes)", address, this.InstructionsMemory().Size()))
+	}
+	this.InstructionsMemory().Store(address, halt...)
+
+	// Code may be generated anywhere on the declared region
+	this.processor.codeEnd = codeEnd
+	if unified.CodeSize > 0 {
+		if unified.CodeBase+unified.CodeSize < codeEnd {
+			return errors.New(fmt.Sprintf("Code region of %d Bytes is smaller than the code loaded (%d Bytes)", unified.CodeSize, codeEnd-unified.CodeBase))
+		}
+		this.processor.codeEnd = unified.CodeBase + unified.CodeSize
 	}
 	return nil
 }
@@ -109,7 +298,8 @@ func (this *Processor) processPreFilledDataMemoryLine(startAddress string, value
 	if err != nil {
 		return errors.New(fmt.Sprintf("Failed parsing memory macro (hex) value: %s. %s", startAddress, err.Error()))
 	}
-	address := uint32(0)
+	// Macros are relative to the data base of a unified memory
+	address := this.Config().DataBase()
 	for i := 0; i < len(bytesStartAddress); i++ {
 		address += uint32(bytesStartAddress[len(bytesStartAddress)-1-i]) << (uint32(i) * 8)
 	}
@@ -126,6 +316,9 @@ func (this *Processor) processPreFilledDataMemoryLine(startAddress string, value
 		for i := 0; i < len(bytes); i++ {
 			value += uint32(bytes[len(bytes)-1-i]) << (uint32(i) * 8)
 		}
+		if !this.DataMemory().InRange(address, consts.BYTES_PER_WORD) {
+			return errors.New(fmt.Sprintf("Address %#04X out of the data memory (%d Bytes)", address, this.DataMemory().Size()))
+		}
 		this.DataMemory().StoreUint32(address, value)
 		address += consts.BYTES_PER_WORD
 	}
@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"app/simulator/processor/config"
+	"app/simulator/translator"
+)
+
+func TestLoadByteOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processor")
+	if err != nil {
+		t.Fatalf("Unexpected error creating the folder: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	// LLI R1, 15: 0x8820000F, macro word at 0x10: 0x11223344
+	assemblyFileName := filepath.Join(dir, "assembly.asm")
+	if err := ioutil.WriteFile(assemblyFileName, []byte("@0x10: 11223344\nLLI R1, 15\n"), 0644); err != nil {
+		t.Fatalf("Unexpected error writing the assembly: %s", err.Error())
+	}
+
+	configs := []struct {
+		fileName string
+		code     []byte
+		data     []byte
+	}{
+		{"default.config", []byte{0x88, 0x20, 0x00, 0x0F}, []byte{0x44, 0x33, 0x22, 0x11}}, // Big-endian code, little-endian data
+		{"endianness/big_endian.config", []byte{0x88, 0x20, 0x00, 0x0F}, []byte{0x11, 0x22, 0x33, 0x44}},
+		{"endianness/little_endian.config", []byte{0x0F, 0x00, 0x20, 0x88}, []byte{0x44, 0x33, 0x22, 0x11}},
+	}
+	for _, expected := range configs {
+		c, err := config.Load(filepath.Join("../../../../samples/configs", expected.fileName))
+		if err != nil {
+			t.Fatalf("Unexpected error loading %s: %s", expected.fileName, err.Error())
+		}
+		hexFileName, err := translator.TranslateFromFile(assemblyFileName, filepath.Join(dir, "assembly.hex"), false, c.CodeBase(), c.InstructionsEndianness(), c.ArchitectureSize(), nil)
+		if err != nil {
+			t.Fatalf("Unexpected error translating with %s: %s", expected.fileName, err.Error())
+		}
+		p, err := New(hexFileName, c)
+		if err != nil {
+			t.Fatalf("Unexpected error loading with %s: %s", expected.fileName, err.Error())
+		}
+
+		if code := p.InstructionsMemory().Load(0, 4); !bytes.Equal(code, expected.code) {
+			t.Errorf("%s instruction bytes expected % X - Got % X", expected.fileName, expected.code, code)
+		}
+		if data := p.DataMemory().Load(0x10, 4); !bytes.Equal(data, expected.data) {
+			t.Errorf("%s macro bytes expected % X - Got % X", expected.fileName, expected.data, data)
+		}
+
+		// Dumps show the words, not the bytes
+		if row := strings.Split(p.InstructionsMemory().ToString(), "\n")[1]; !strings.HasPrefix(row, "0x00\t0x8820000F") {
+			t.Errorf("%s instructions dump expected 0x8820000F - Got %s", expected.fileName, row)
+		}
+		if row := strings.Split(p.DataMemory().ToString(), "\n")[2]; !strings.HasPrefix(row, "0x10\t0x11223344") {
+			t.Errorf("%s data dump expected 0x11223344 - Got %s", expected.fileName, row)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package processor
+
+// Branch predictors register themselves by name when their package is imported,
+// the package of a new predictor only needs to be added here to be selected on the config
+import (
+	_ "app/simulator/processor/components/branchhistory"
+	_ "app/simulator/processor/components/staticpredictor"
+)
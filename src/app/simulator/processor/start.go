@@ -7,6 +7,7 @@ import (
 	"app/simulator/processor/components/pipeline/dispatcher"
 	"app/simulator/processor/components/pipeline/executor"
 	"app/simulator/processor/components/pipeline/fetcher"
+	"app/simulator/processor/components/predictor"
 	"app/simulator/processor/config"
 	"app/simulator/processor/models/info"
 	"app/simulator/processor/models/operation"
@@ -20,7 +21,7 @@ func (this *Processor) Start() {
 
 	// Launch pipeline units and execute instruction 0x0000
 	recoveryChannel := channel.New(1)
-	flushFunc := this.StartPipelineUnits(this.Config(), recoveryChannel, 0, 0x0000)
+	flushFunc := this.StartPipelineUnits(this.Config(), recoveryChannel, 0, this.Config().CodeBase())
 
 	// Launch clock
 	go this.RunClock()
@@ -134,6 +135,12 @@ func (this *Processor) RunRecovery(recoveryChannel channel.Channel, flushFunc fu
 		this.RemoveForwardLogs(op.Id() - 1)
 		// Clear speculative jumps
 		this.ClearSpeculativeJumps()
+		// Restore hardware loops to the committed state
+		this.RestoreHardwareLoops()
+		// Repair the speculative branch history
+		if speculative, ok := this.Predictor().(predictor.Speculative); ok {
+			speculative.Restore()
+		}
 		// Start pipeline from the recovery address
 		flushFunc = this.StartPipelineUnits(this.Config(), recoveryChannel, op.Id(), op.Address())
 		// Release value from channel
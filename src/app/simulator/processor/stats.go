@@ -7,7 +7,14 @@ import (
 	"strings"
 
 	"app/logger"
+	"app/simulator/processor/components/cache"
+	"app/simulator/processor/components/device"
+	"app/simulator/processor/components/dram"
+	"app/simulator/processor/components/predictor"
 	"app/simulator/processor/config"
+	"app/simulator/processor/consts"
+	"app/simulator/processor/models/branchreport"
+	"app/simulator/processor/models/branchtrace"
 )
 
 type LogEvent struct {
@@ -23,12 +30,37 @@ func NewEvent(id string, start uint32, end uint32) LogEvent {
 func (this *Processor) Stats() string {
 	logger.SetVerboseQuiet(false)
 	stats := "\n Program Stats:\n\n"
+	if this.Fault() != nil {
+		stats += fmt.Sprintf(" => Stopped by fault: %s\n\n", this.Fault().Error())
+	}
 	stats += fmt.Sprintf(" => Instructions found: %d\n", len(this.InstructionsMap()))
 	stats += fmt.Sprintf(" => Instructions executed: %d\n", this.InstructionsCompletedCounter())
 	stats += fmt.Sprintf(" => Cycles performed: %d\n", this.Cycles())
 	stats += fmt.Sprintf(" => Cycles per instruction: %3.2f cycles\n", float32(this.Cycles())/float32(this.InstructionsCompletedCounter()))
 	stats += fmt.Sprintf(" => Simulation duration: %d ms\n", this.DurationMs())
 	stats += fmt.Sprintf("\n")
+	stats += fmt.Sprintf(" => Loads: %d\n", this.processor.loadOperations)
+	stats += fmt.Sprintf(" => Stores: %d\n", this.processor.storeOperations)
+	stats += fmt.Sprintf(" => ROB Full Stall Cycles: %d\n", this.processor.robFullStalls)
+	if this.Config().LoadStoreQueue() {
+		stats += fmt.Sprintf(" => Store-to-Load Forwards: %d (%d partial)\n", this.processor.storeForwards, this.processor.partialStoreForwards)
+		stats += fmt.Sprintf(" => Memory Ordering Violations: %d\n", this.processor.orderingViolations)
+		stats += fmt.Sprintf(" => Load Replays: %d\n", this.processor.loadReplays)
+		stats += fmt.Sprintf(" => LSQ Full Stall Cycles: %d\n", this.processor.loadStoreQueueStalls)
+	}
+	if this.Config().UnifiedMemory() != nil {
+		stats += fmt.Sprintf(" => Code Modifications (flushes): %d\n", this.processor.codeModifications)
+	}
+	if this.MemoryPorts() != nil {
+		ports := this.MemoryPorts()
+		stats += fmt.Sprintf(" => Memory Port Accesses: %d (%d reads, %d writes)\n", ports.Reads()+ports.Writes(), ports.Reads(), ports.Writes())
+		stats += fmt.Sprintf(" => Memory Port Busy Cycles: %d (%d read, %d write)\n", ports.ReadPortBusyCycles()+ports.WritePortBusyCycles(), ports.ReadPortBusyCycles(), ports.WritePortBusyCycles())
+		stats += fmt.Sprintf(" => Memory Bank Conflict Cycles: %d\n", ports.BankConflictCycles())
+		for bank, accesses := range ports.BankAccesses() {
+			stats += fmt.Sprintf(" => Memory Bank %d Accesses: %d\n", bank, accesses)
+		}
+	}
+	stats += fmt.Sprintf("\n")
 	totalBranches := this.processor.conditionalBranches + this.processor.unconditionalBranches
 	stats += fmt.Sprintf(" => Total Branches: %d\n", totalBranches)
 	stats += fmt.Sprintf(" => Conditional Branches: %d\n", this.processor.conditionalBranches)
@@ -38,10 +70,131 @@ func (this *Processor) Stats() string {
 		stats += fmt.Sprintf(" => No Taken Unconditional Branches: %d\n", this.processor.noTakenBranches)
 		stats += fmt.Sprintf(" => Mispredicted Branches: %d\n", this.processor.mispredictedBranches)
 		stats += fmt.Sprintf(" => Misprediction Percentage (Conditional): %3.2f\n", 100*float32(this.processor.mispredictedBranches)/float32(this.processor.conditionalBranches))
+		stats += fmt.Sprintf(" => Mispredictions per Kilo Instructions (MPKI): %3.2f\n", 1000*float32(this.processor.mispredictedBranches)/float32(this.InstructionsCompletedCounter()))
+		stats += fmt.Sprintf(" => Misprediction Stall Cycles: %d (out of %d stall cycles)\n", this.BranchReport().StallCycles(), this.StallCycles())
+		worst := this.BranchReport().Worst(branchreport.WORST_BRANCHES)
+		if len(worst) > 0 {
+			stats += fmt.Sprintf(" => Hardest Branches to Predict:\n")
+		}
+		for _, record := range worst {
+			stats += fmt.Sprintf("    0x%04X %-24s %d mispredictions out of %d (%3.2f%%), %d stall cycles\n",
+				record.Address, record.Source, record.Mispredictions, record.Executions, 100*record.MispredictionRate(), record.StallCycles)
+		}
+	}
+	if reporter, ok := this.Predictor().(predictor.Reporter); ok {
+		stats += reporter.Stats()
+	}
+	if this.BranchTargetBuffer() != nil {
+		btb := this.BranchTargetBuffer()
+		stats += fmt.Sprintf(" => BTB Lookups: %d (%d more on wrong paths)\n", btb.Lookups(), btb.WrongPathLookups())
+		stats += fmt.Sprintf(" => BTB Hits: %d (%d wrong targets)\n", btb.Hits(), btb.WrongTargets())
+		stats += fmt.Sprintf(" => BTB Hit Rate: %3.2f%%\n", 100*btb.HitRate())
+		stats += fmt.Sprintf(" => BTB Miss Bubbles: %d\n", btb.MissBubbles())
+	}
+	for _, level := range this.MemoryLevels() {
+		stats += fmt.Sprintf("\n")
+		switch level := level.(type) {
+		case *cache.Cache:
+			stats += this.cacheStats(level)
+		case *dram.DRAM:
+			stats += this.dramStats(level)
+		}
+		if level == this.InstructionMemoryLevel() {
+			stats += fmt.Sprintf(" => Fetch Cycles Lost to %s Misses: %d\n", level.Name(), this.processor.fetchStallCycles)
+		}
+	}
+	if this.DataPrefetcher() != nil {
+		prefetcher := this.DataPrefetcher()
+		stats += fmt.Sprintf("\n")
+		stats += fmt.Sprintf(" => Prefetches Issued: %d\n", prefetcher.Issued())
+		stats += fmt.Sprintf(" => Useful Prefetches: %d\n", prefetcher.Useful())
+		stats += fmt.Sprintf(" => Late Prefetches: %d (%d cycles)\n", prefetcher.Late(), prefetcher.LateCycles())
+		stats += fmt.Sprintf(" => Prefetch Accuracy: %3.2f%%\n", 100*prefetcher.Accuracy())
+		stats += fmt.Sprintf(" => Prefetch Coverage: %3.2f%%\n", 100*prefetcher.Coverage())
+		stats += fmt.Sprintf(" => Prefetch Timeliness: %3.2f%%\n", 100*prefetcher.Timeliness())
+	}
+	if this.MMU() != nil {
+		stats += fmt.Sprintf("\n")
+		for _, tlb := range this.MMU().TLBs() {
+			name := tlb.Name()
+			stats += fmt.Sprintf(" => %s Accesses: %d\n", name, tlb.Accesses())
+			stats += fmt.Sprintf(" => %s Hits: %d\n", name, tlb.Hits())
+			stats += fmt.Sprintf(" => %s Misses: %d\n", name, tlb.Misses())
+			stats += fmt.Sprintf(" => %s Hit Rate: %3.2f%%\n", name, 100*tlb.HitRate())
+		}
+		stats += fmt.Sprintf(" => Page Walks: %d\n", this.MMU().PageWalks())
+		stats += fmt.Sprintf(" => Page Walk Cycles: %d\n", this.MMU().PageWalkCycles())
+		stats += fmt.Sprintf(" => Page Faults Detected: %d\n", this.MMU().PageFaults())
+		stats += fmt.Sprintf(" => Page Table Stores (flushes): %d\n", this.MMU().PageTableStores())
+	}
+	if this.Config().CompressedInstructions() {
+		// Same instructions without compression take a full word each
+		uncompressedSize := uint32(len(this.InstructionsMap())) * consts.BYTES_PER_WORD
+		fetchBlocks := float32(this.processor.fetchBlocks)
+		stats += fmt.Sprintf("\n")
+		stats += fmt.Sprintf(" => Code Size: %d bytes\n", this.processor.codeSize)
+		stats += fmt.Sprintf(" => Code Size (uncompressed): %d bytes\n", uncompressedSize)
+		stats += fmt.Sprintf(" => Code Size Reduction: %3.2f%%\n", 100*(1-float32(this.processor.codeSize)/float32(uncompressedSize)))
+		stats += fmt.Sprintf(" => Fetch Blocks: %d\n", this.processor.fetchBlocks)
+		stats += fmt.Sprintf(" => Bytes per Instruction Fetched: %3.2f\n", float32(this.processor.fetchedBytes)/float32(this.processor.fetchedInstructions))
+		stats += fmt.Sprintf(" => Fetch Bandwidth: %3.2f instructions per block\n", float32(this.processor.fetchedInstructions)/fetchBlocks)
+		stats += fmt.Sprintf(" => Fetch Bandwidth (uncompressed, estimated): %3.2f instructions per block\n", float32(this.processor.fetchedBytes)/consts.BYTES_PER_WORD/fetchBlocks)
+	}
+	if this.processor.hardwareLoopsStarted > 0 {
+		// Every iteration goes back to the loop start without a branch, the cycles saved are measured
+		// by running the branch-based form of the program
+		stats += fmt.Sprintf("\n")
+		stats += fmt.Sprintf(" => Hardware Loops: %d\n", this.processor.hardwareLoopsStarted)
+		stats += fmt.Sprintf(" => Hardware Loop Iterations: %d\n", this.processor.hardwareLoopIterations)
+		stats += fmt.Sprintf(" => Branches Eliminated: %d\n", this.processor.hardwareLoopIterations)
+	}
+	if this.processor.atomicOperations+this.processor.fenceOperations > 0 {
+		stats += fmt.Sprintf("\n")
+		stats += fmt.Sprintf(" => Atomic Operations: %d\n", this.processor.atomicOperations)
+		stats += fmt.Sprintf(" => Fence Operations: %d\n", this.processor.fenceOperations)
+		stats += fmt.Sprintf(" => Failed Store Conditionals: %d\n", this.processor.failedStoreConditionals)
+		stats += fmt.Sprintf(" => Serialization Stall Cycles: %d\n", this.processor.serializationCycles)
+	}
+	if len(this.Devices().Devices()) > 0 {
+		stats += fmt.Sprintf("\n")
+		for _, d := range this.Devices().Devices() {
+			stats += fmt.Sprintf(" => Device %s Accesses: %d reads, %d writes\n", d.Name(), d.Reads(), d.Writes())
+		}
+		stats += fmt.Sprintf(" => Device Stall Cycles: %d\n", this.processor.deviceStallCycles)
 	}
 	return stats
 }
 
+// Cycles lost to every kind of stall: mispredictions, full ROB & LSQ, serialization, fetch misses and devices
+func (this *Processor) StallCycles() uint32 {
+	return this.BranchReport().StallCycles() + this.processor.robFullStalls + this.processor.loadStoreQueueStalls +
+		this.processor.serializationCycles + this.processor.fetchStallCycles + this.processor.deviceStallCycles
+}
+
+func (this *Processor) cacheStats(c *cache.Cache) string {
+	name := c.Name()
+	stats := fmt.Sprintf(" => %s Accesses: %d (%d reads, %d writes)\n", name, c.Accesses(), c.Reads(), c.Writes())
+	stats += fmt.Sprintf(" => %s Hits: %d\n", name, c.Hits())
+	stats += fmt.Sprintf(" => %s Misses: %d\n", name, c.Misses())
+	stats += fmt.Sprintf(" => %s Hit Rate: %3.2f%%\n", name, 100*c.HitRate())
+	stats += fmt.Sprintf(" => %s Miss Rate: %3.2f%%\n", name, 100*(1-c.HitRate()))
+	stats += fmt.Sprintf(" => %s Misses per Kilo-Instruction (MPKI): %3.2f\n", name, 1000*float32(c.Misses())/float32(this.InstructionsCompletedCounter()))
+	stats += fmt.Sprintf(" => %s Evictions: %d\n", name, c.Evictions())
+	stats += fmt.Sprintf(" => %s Write-backs: %d\n", name, c.WriteBacks())
+	stats += fmt.Sprintf(" => %s Memory Writes: %d\n", name, c.MemoryWrites())
+	return stats
+}
+
+func (this *Processor) dramStats(d *dram.DRAM) string {
+	name := d.Name()
+	stats := fmt.Sprintf(" => %s Accesses: %d (%d reads, %d writes)\n", name, d.Accesses(), d.Reads(), d.Writes())
+	stats += fmt.Sprintf(" => %s Row Hits: %d\n", name, d.RowHits())
+	stats += fmt.Sprintf(" => %s Row Misses: %d\n", name, d.RowMisses())
+	stats += fmt.Sprintf(" => %s Row Conflicts: %d\n", name, d.RowConflicts())
+	stats += fmt.Sprintf(" => %s Row Hit Rate: %3.2f%%\n", name, 100*d.RowHitRate())
+	return stats
+}
+
 func (this *Processor) PipelineFlow() string {
 
 	instructions := this.InstructionsFetchedCounter()
@@ -116,6 +269,48 @@ func (this *Processor) SaveOutputFiles(outputFolder string) error {
 	}
 	logger.Print(" => Pipeline flow saved at %s", filename)
 
+	// Save memory trace
+	if this.MemoryTrace() != nil {
+		// Accesses not committed by the end of the run never retire
+		this.MemoryTrace().Squash(0)
+		filename = filepath.Join(outputFolder, this.MemoryTrace().Filename())
+		err = ioutil.WriteFile(filename, this.MemoryTrace().Encode(), 0644)
+		if err != nil {
+			return err
+		}
+		logger.Print(" => Memory trace (%s) saved at %s", this.MemoryTrace().Format(), filename)
+	}
+
+	// Save branch trace
+	if this.BranchTrace() != nil {
+		filename = filepath.Join(outputFolder, branchtrace.FILENAME)
+		err = ioutil.WriteFile(filename, this.BranchTrace().Encode(this.InstructionsCompletedCounter()), 0644)
+		if err != nil {
+			return err
+		}
+		logger.Print(" => Branch trace saved at %s", filename)
+	}
+
+	// Save consoles output
+	for _, d := range this.Devices().Devices() {
+		if console, ok := d.(*device.Console); ok {
+			filename = filepath.Join(outputFolder, fmt.Sprintf("%s.log", console.Name()))
+			err = ioutil.WriteFile(filename, []byte(console.Output()), 0644)
+			if err != nil {
+				return err
+			}
+			logger.Print(" => Console output (%s) saved at %s", console.Name(), filename)
+		}
+	}
+
+	// Save per branch report
+	filename = filepath.Join(outputFolder, branchreport.FILENAME)
+	err = ioutil.WriteFile(filename, this.BranchReport().Encode(this.StallCycles()), 0644)
+	if err != nil {
+		return err
+	}
+	logger.Print(" => Branch report saved at %s", filename)
+
 	// Save stats
 	filename = filepath.Join(outputFolder, "output.log")
 	err = ioutil.WriteFile(filename, []byte(this.Config().ToString()+this.Stats()), 0644)
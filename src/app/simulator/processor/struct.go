@@ -3,14 +3,24 @@ package processor
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"app/logger"
-	"app/simulator/processor/components/branchpredictor"
+	"app/simulator/processor/components/branchtargetbuffer"
+	"app/simulator/processor/components/cache"
 	"app/simulator/processor/components/clock"
+	"app/simulator/processor/components/device"
 	"app/simulator/processor/components/memory"
+	"app/simulator/processor/components/memoryports"
+	"app/simulator/processor/components/mmu"
+	"app/simulator/processor/components/predictor"
+	"app/simulator/processor/components/prefetcher"
 	"app/simulator/processor/config"
 	"app/simulator/processor/consts"
+	"app/simulator/processor/models/branchreport"
+	"app/simulator/processor/models/branchtrace"
+	"app/simulator/processor/models/memorytrace"
 	"app/simulator/processor/models/set"
 )
 
@@ -18,23 +28,71 @@ type Processor struct {
 	*processor
 }
 
+type HardwareLoop struct {
+	Start uint32
+	End   uint32
+	Count uint32
+}
+
 type processor struct {
 	// internals
 	done                     bool
+	fault                    error
 	instructionsFetched      []string
 	instructionsCompleted    []uint32
 	lastOperationIdCompleted uint32
 	dataLog                  map[uint32][]LogEvent
 
 	// Branch stats
-	branchHistoryTable    map[uint32]uint32
-	branchPredictorBits   uint32
+	predictor             predictor.Predictor
+	branchTargetBuffer    *branchtargetbuffer.BranchTargetBuffer
+	branchReport          *branchreport.BranchReport
 	conditionalBranches   uint32
 	unconditionalBranches uint32
 	mispredictedBranches  uint32
 	noTakenBranches       uint32
 	speculativeJumps      uint32
 
+	// Memory stats
+	loadOperations  uint32
+	storeOperations uint32
+	robFullStalls   uint32
+	// Last cycle counted as a ROB full stall, plus one so the first cycle is counted too
+	robFullStallCycle uint32
+	robFullStallLock  sync.Mutex
+
+	// Load/store queue stats
+	storeForwards        uint32
+	partialStoreForwards uint32
+	orderingViolations   uint32
+	loadReplays          uint32
+	loadStoreQueueStalls uint32
+
+	// Device stats
+	deviceStallCycles uint32
+
+	// Atomic stats
+	reservationAddress      uint32
+	reservationValid        bool
+	atomicOperations        uint32
+	fenceOperations         uint32
+	failedStoreConditionals uint32
+	serializationCycles     uint32
+
+	// Fetch stats
+	codeSize            uint32
+	codeModifications   uint32
+	fetchBlocks         uint32
+	fetchedInstructions uint32
+	fetchedBytes        uint32
+	fetchStallCycles    uint32
+
+	// Hardware loops
+	hardwareLoops            []HardwareLoop
+	speculativeHardwareLoops []HardwareLoop
+	hardwareLoopsStarted     uint32
+	hardwareLoopIterations   uint32
+
 	// metadata
 	instructionsMap map[uint32]string
 	instructionsSet set.Set
@@ -45,9 +103,21 @@ type processor struct {
 
 	// data/memory
 	programCounter    uint32
+	codeEnd           uint32
 	registerMemory    *memory.Memory
 	instructionMemory *memory.Memory
 	dataMemory        *memory.Memory
+	dataCache         *cache.Cache
+	instructionCache  *cache.Cache
+	dataLevel         cache.Level
+	instructionLevel  cache.Level
+	memoryLevels      []cache.Level
+	devices           *device.Bus
+	mmu               *mmu.MMU
+	dataPrefetcher    *prefetcher.Prefetcher
+	memoryPorts       *memoryports.MemoryPorts
+	memoryTrace       *memorytrace.MemoryTrace
+	branchTrace       *branchtrace.BranchTrace
 }
 
 ///////////////////////////
@@ -59,6 +129,17 @@ func (this *Processor) Finish() {
 	this.processor.done = true
 }
 
+// Stops the processor on a committed fault
+func (this *Processor) RaiseFault(fault error) {
+	logger.Collect(" => %s", fault.Error())
+	logger.Collect(" => Stopping processor...")
+	this.processor.fault = fault
+}
+
+func (this *Processor) Fault() error {
+	return this.processor.fault
+}
+
 func (this *Processor) InstructionsFetched() []string {
 	return this.processor.instructionsFetched
 }
@@ -137,20 +218,121 @@ func (this *Processor) LogEventFinish(unit string, index uint32, operationId uin
 	}
 }
 
-func (this *Processor) LogBranchInstruction(address uint32, conditionalBranch, mispredicted bool, taken bool) {
+func (this *Processor) LogBranchInstruction(operationId uint32, address uint32, conditionalBranch, mispredicted bool, taken bool, target uint32) {
 	if conditionalBranch {
 		this.processor.conditionalBranches += 1
 		if !taken {
 			this.processor.noTakenBranches += 1
 		}
-		currState, _ := this.GetBranchStateByAddress(address)
-		nextState := branchpredictor.GetNextState(currState, this.processor.branchPredictorBits, taken)
-		this.processor.branchHistoryTable[address] = nextState
+		if this.Predictor() != nil {
+			this.Predictor().Update(address, taken, target, taken != mispredicted)
+		}
 	} else {
 		this.processor.unconditionalBranches += 1
 	}
+	if this.BranchTargetBuffer() != nil {
+		this.BranchTargetBuffer().Commit(operationId)
+	}
+	stallCycles := uint32(0)
 	if mispredicted {
 		this.processor.mispredictedBranches += 1
+		// Wrong path from the resolution of the branch until the redirect after its writeback
+		stallCycles = this.Cycles() + consts.WRITEBACK_CYCLES - this.resolveCycle(operationId)
+	}
+	// Unconditional branches are always taken
+	this.BranchReport().Log(address, this.sourceLine(address), taken || !conditionalBranch, mispredicted, stallCycles)
+}
+
+// Cycle a branch left the branch unit, when its outcome was known
+func (this *Processor) resolveCycle(operationId uint32) uint32 {
+	for _, event := range this.processor.dataLog[operationId] {
+		if strings.HasPrefix(event.Id, consts.BRANCH_EVENT) {
+			return event.End
+		}
+	}
+	return this.Cycles()
+}
+
+// Human readable instruction at an address, as written on the hex file
+func (this *Processor) sourceLine(address uint32) string {
+	value, ok := this.InstructionsMap()[address]
+	if !ok {
+		return ""
+	}
+	parts := strings.Split(value, "=>")
+	return strings.Join(strings.Fields(parts[len(parts)-1]), " ")
+}
+
+func (this *Processor) LogAtomicInstruction(fence bool, failed bool) {
+	if fence {
+		this.processor.fenceOperations += 1
+	} else {
+		this.processor.atomicOperations += 1
+	}
+	if failed {
+		this.processor.failedStoreConditionals += 1
+	}
+}
+
+func (this *Processor) LogSerializationStall(cycles uint32) {
+	this.processor.serializationCycles += cycles
+}
+
+func (this *Processor) LogFetchBlock(instructions uint32, bytes uint32) {
+	if instructions > 0 {
+		this.processor.fetchBlocks += 1
+		this.processor.fetchedInstructions += instructions
+		this.processor.fetchedBytes += bytes
+	}
+}
+
+func (this *Processor) LogFetchStall(cycles uint32) {
+	this.processor.fetchStallCycles += cycles
+}
+
+// Every operation waiting for a ROB entry logs the stall, each cycle is only counted once
+func (this *Processor) LogRobFullStall() {
+	this.processor.robFullStallLock.Lock()
+	defer this.processor.robFullStallLock.Unlock()
+	if this.processor.robFullStallCycle != this.Cycles()+1 {
+		this.processor.robFullStallCycle = this.Cycles() + 1
+		this.processor.robFullStalls += 1
+	}
+}
+
+func (this *Processor) LogStoreForward(partial bool) {
+	this.processor.storeForwards += 1
+	if partial {
+		this.processor.partialStoreForwards += 1
+	}
+}
+
+func (this *Processor) LogOrderingViolation() {
+	this.processor.orderingViolations += 1
+}
+
+func (this *Processor) LogLoadReplay() {
+	this.processor.loadReplays += 1
+}
+
+func (this *Processor) LogCodeModification() {
+	this.processor.codeModifications += 1
+}
+
+func (this *Processor) LogLoadStoreQueueFullStall() {
+	this.processor.loadStoreQueueStalls += 1
+}
+
+func (this *Processor) LogDeviceStall(cycles uint32) {
+	this.processor.deviceStallCycles += cycles
+}
+
+func (this *Processor) LogMemoryInstruction(load bool, store bool) {
+	if load {
+		this.processor.loadOperations += 1
+	}
+	if store {
+		this.processor.storeOperations += 1
 	}
 }
 
@@ -175,15 +357,24 @@ func (this *Processor) RemoveForwardLogs(operationId uint32) {
 	for _, opId := range opsIdToDelete {
 		delete(this.processor.dataLog, opId)
 	}
+
+	// Remove forward ops from the branch target buffer lookups
+	if this.BranchTargetBuffer() != nil {
+		this.BranchTargetBuffer().Flush(operationId)
+	}
+
+	// Forward ops accesses were wrong-path
+	if this.MemoryTrace() != nil {
+		this.MemoryTrace().Squash(operationId + 1)
+	}
 }
 
-func (this *Processor) ReachedEnd(bytes []byte) bool {
-	for _, b := range bytes {
-		if b != consts.ENDING_BYTE {
-			return false
-		}
+// Programs end once a halt is fetched, the loader places one right after the code
+func (this *Processor) ReachedEnd(address uint32, bytes []byte) bool {
+	if uint32(len(bytes)) != consts.BYTES_PER_WORD {
+		return false
 	}
-	return true
+	return set.IsHalt(uint32(memory.Decode(bytes, this.Config().InstructionsEndianness())))
 }
 
 ///////////////////////////
@@ -210,6 +401,85 @@ func (this *Processor) DataMemory() *memory.Memory {
 	return this.processor.dataMemory
 }
 
+func (this *Processor) DataCache() *cache.Cache {
+	return this.processor.dataCache
+}
+
+func (this *Processor) InstructionCache() *cache.Cache {
+	return this.processor.instructionCache
+}
+
+func (this *Processor) DataMemoryLevel() cache.Level {
+	return this.processor.dataLevel
+}
+
+func (this *Processor) InstructionMemoryLevel() cache.Level {
+	return this.processor.instructionLevel
+}
+
+func (this *Processor) MemoryLevels() []cache.Level {
+	return this.processor.memoryLevels
+}
+
+func (this *Processor) Devices() *device.Bus {
+	return this.processor.devices
+}
+
+func (this *Processor) MMU() *mmu.MMU {
+	return this.processor.mmu
+}
+
+// Whether the bytes overlap the code region of a unified memory (the code loaded unless the config declares a larger one)
+func (this *Processor) IsCode(address, size uint32) bool {
+	if this.Config().UnifiedMemory() == nil {
+		return false
+	}
+	return address < this.processor.codeEnd && address+size > this.Config().CodeBase()
+}
+
+func (this *Processor) Predictor() predictor.Predictor {
+	return this.processor.predictor
+}
+
+func (this *Processor) BranchTargetBuffer() *branchtargetbuffer.BranchTargetBuffer {
+	return this.processor.branchTargetBuffer
+}
+
+func (this *Processor) BranchReport() *branchreport.BranchReport {
+	return this.processor.branchReport
+}
+
+func (this *Processor) DataPrefetcher() *prefetcher.Prefetcher {
+	return this.processor.dataPrefetcher
+}
+
+func (this *Processor) MemoryPorts() *memoryports.MemoryPorts {
+	return this.processor.memoryPorts
+}
+
+func (this *Processor) MemoryTrace() *memorytrace.MemoryTrace {
+	return this.processor.memoryTrace
+}
+
+// Every load and store executed from now on is written to a trace file with the output files
+func (this *Processor) EnableMemoryTrace(format memorytrace.Format) error {
+	memoryTrace, err := memorytrace.New(format)
+	if err != nil {
+		return err
+	}
+	this.processor.memoryTrace = memoryTrace
+	return nil
+}
+
+func (this *Processor) BranchTrace() *branchtrace.BranchTrace {
+	return this.processor.branchTrace
+}
+
+// Every conditional branch committed from now on is written to a trace file with the output files
+func (this *Processor) EnableBranchTrace() {
+	this.processor.branchTrace = branchtrace.New()
+}
+
 func (this *Processor) InstructionsMemory() *memory.Memory {
 	return this.processor.instructionMemory
 }
@@ -234,16 +504,85 @@ func (this *Processor) IncrementProgramCounter(offset int32) {
 	}
 }
 
-func (this *Processor) SetPredictorBits(bits uint32) {
-	this.processor.branchPredictorBits = bits
+func (this *Processor) PerformanceCounter(index uint32) uint32 {
+	switch index {
+	case consts.PMC_CYCLES:
+		return this.Cycles()
+	case consts.PMC_INSTRUCTIONS:
+		return this.InstructionsCompletedCounter()
+	case consts.PMC_MISPREDICTIONS:
+		return this.processor.mispredictedBranches
+	case consts.PMC_ROB_FULL_STALLS:
+		return this.processor.robFullStalls
+	case consts.PMC_LOADS:
+		return this.processor.loadOperations
+	case consts.PMC_STORES:
+		return this.processor.storeOperations
+	}
+	logger.Collect(" => Unknown performance counter PMC%d", index)
+	return 0
+}
+
+func (this *Processor) SetReservation(address uint32) {
+	this.processor.reservationAddress = address
+	this.processor.reservationValid = true
+}
+
+func (this *Processor) CheckReservation(address uint32) bool {
+	return this.processor.reservationValid && this.processor.reservationAddress == address
+}
+
+func (this *Processor) ClearReservation() {
+	this.processor.reservationValid = false
+}
+
+func (this *Processor) HardwareLoops() []HardwareLoop {
+	return this.processor.hardwareLoops
+}
+
+// Loops past the maximum depth fault before they commit, see HardwareLoopDepthReached
+func (this *Processor) PushHardwareLoop(start uint32, end uint32, count uint32) {
+	this.processor.hardwareLoopsStarted += 1
+	this.processor.hardwareLoops = append(this.processor.hardwareLoops, HardwareLoop{Start: start, End: end, Count: count})
+	// Fetch is stalled until the loop is set up, so speculative loops are the committed ones
+	this.RestoreHardwareLoops()
+}
+
+func (this *Processor) HardwareLoopDepthReached() bool {
+	return uint32(len(this.processor.hardwareLoops)) >= this.Config().HardwareLoopDepth()
+}
+
+func (this *Processor) CommitHardwareLoop(nextAddress uint32) (uint32, bool) {
+	loops := this.processor.hardwareLoops
+	if len(loops) > 0 && loops[len(loops)-1].End == nextAddress {
+		this.processor.hardwareLoopIterations += 1
+	}
+	loops, start, looping := nextHardwareLoop(loops, nextAddress)
+	this.processor.hardwareLoops = loops
+	return start, looping
+}
+
+func (this *Processor) NextHardwareLoopAddress(nextAddress uint32) (uint32, bool) {
+	loops, start, looping := nextHardwareLoop(this.processor.speculativeHardwareLoops, nextAddress)
+	this.processor.speculativeHardwareLoops = loops
+	return start, looping
 }
 
-func (this *Processor) GetBranchStateByAddress(address uint32) (uint32, bool) {
-	state, exist := this.processor.branchHistoryTable[address]
-	if !exist {
-		return 0, false
+func (this *Processor) RestoreHardwareLoops() {
+	this.processor.speculativeHardwareLoops = append([]HardwareLoop{}, this.processor.hardwareLoops...)
+}
+
+func nextHardwareLoop(loops []HardwareLoop, nextAddress uint32) ([]HardwareLoop, uint32, bool) {
+	// Innermost loops are on top, nested loops may share the same end address
+	for len(loops) > 0 && loops[len(loops)-1].End == nextAddress {
+		top := &loops[len(loops)-1]
+		top.Count -= 1
+		if top.Count > 0 {
+			return loops, top.Start, true
+		}
+		loops = loops[:len(loops)-1]
 	}
-	return state, true
+	return loops, 0, false
 }
 
 func (this *Processor) SpeculativeJumps() uint32 {
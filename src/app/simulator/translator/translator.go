@@ -8,12 +8,19 @@ import (
 	"strings"
 
 	"app/logger"
+	"app/simulator/processor/components/memory"
+	"app/simulator/processor/config"
 	"app/simulator/processor/consts"
+	"app/simulator/processor/models/branchreport"
 	"app/simulator/processor/models/set"
 	"app/utils"
 )
 
-func TranslateFromFile(filename string, outputFilename string) (string, error) {
+// Instructions are assembled from the base address, labels are absolute addresses
+// Hex values are written as laid out in memory, in the given endianness
+// Conditional branches get their hint bits from the profile of a previous run, if any
+// Doubleword instructions are rejected on a 32-bit architecture
+func TranslateFromFile(filename string, outputFilename string, compressed bool, base uint32, endianness config.Endianness, architectureSize uint32, profile []*branchreport.Record) (string, error) {
 
 	// Read lines from file
 	logger.Print(" => Reading assembly file: %s", filename)
@@ -30,32 +37,149 @@ func TranslateFromFile(filename string, outputFilename string) (string, error) {
 	defer f.Close()
 
 	// Clean lines, remove labels and get map of labels
-	memory, lines, labels := getLinesAndMapLabels(lines)
+	macros, lines, labelLines := getLinesAndMapLabels(lines)
 
 	// Print pre-filled memory macros
-	for _, line := range memory {
+	for _, line := range macros {
 		f.WriteString(fmt.Sprintf("%s\n", line))
 	}
 
-	// Translate instructions
+	// Get which instructions can be compressed
 	instructionSet := set.Init()
+	alignment := uint32(consts.BYTES_PER_WORD)
+	if compressed {
+		alignment = consts.BYTES_PER_HALFWORD
+	}
+	sizes, err := getInstructionSizes(instructionSet, lines, labelLines, alignment, compressed, base)
+	if err != nil {
+		return "", err
+	}
+	if profile != nil && compressed {
+		// Hinted opcodes are taken by the 16 bits encodings
+		return "", errors.New("Branch hints are not available with compressed instructions, a branch profile can not be used")
+	}
+	if profile != nil {
+		// Profiled addresses are the ones without hints
+		addresses, _ := getAddressesAndLabels(sizes, labelLines, base)
+		lines = setBranchHints(instructionSet, lines, addresses, profile)
+		sizes, err = getInstructionSizes(instructionSet, lines, labelLines, alignment, compressed, base)
+		if err != nil {
+			return "", err
+		}
+	}
+	addresses, labels := getAddressesAndLabels(sizes, labelLines, base)
+
+	// Translate instructions
 	for i, line := range lines {
-		instruction, err := instructionSet.GetInstructionFromString(line, uint32(i)*consts.BYTES_PER_WORD, labels)
+		instruction, err := instructionSet.GetInstructionFromString(line, addresses[i]+sizes[i], alignment, labels)
 		if err != nil {
 			return "", errors.New(fmt.Sprintf("Failed translating line %d: %s. %s", i, line, err.Error()))
 		}
-		hex := fmt.Sprintf("%08X", instruction.ToUint32())
-		f.WriteString(fmt.Sprintf("%s // 0x%04X => %s\n", hex, i*consts.BYTES_PER_WORD, strings.Replace(line, "\t", " ", -1)))
+		if set.IsDoubleword(instruction.Info.Opcode) && architectureSize < 64 {
+			return "", errors.New(fmt.Sprintf("Failed translating line %d: %s. Doubleword instructions require a 64-bit architecture", i, line))
+		}
+		hex := fmt.Sprintf("%X", memory.Encode(uint64(instruction.ToUint32()), consts.BYTES_PER_WORD, endianness))
+		if sizes[i] == consts.BYTES_PER_HALFWORD {
+			bytes, _ := set.Compress(instruction, addresses[i])
+			hex = fmt.Sprintf("%02X%02X", bytes[0], bytes[1])
+		}
+		f.WriteString(fmt.Sprintf("%s // 0x%04X => %s\n", hex, addresses[i], strings.Replace(line, "\t", " ", -1)))
 	}
 	logger.Print(" => Output hex file: %s", outputFilename)
 	return outputFilename, nil
 }
 
+// Branches taken most of the times in the profile are hinted likely and the rest unlikely,
+// a profiled address holding another instruction is skipped
+func setBranchHints(instructionSet set.Set, lines []string, addresses []uint32, profile []*branchreport.Record) []string {
+	records := map[uint32]*branchreport.Record{}
+	for _, record := range profile {
+		records[record.Address] = record
+	}
+	hinted := map[set.BranchHint]uint32{}
+	for i, line := range lines {
+		record, ok := records[addresses[i]]
+		if !ok || record.Executions == 0 {
+			continue
+		}
+		mnemonic := strings.Fields(line)[0]
+		opInfo, err := instructionSet.GetInstructionInfoFromName(mnemonic)
+		if err != nil || !opInfo.IsConditionalBranch() {
+			continue
+		}
+		name := set.GetHintedBranchName(mnemonic, set.NoHint)
+		if len(strings.Fields(record.Source)) == 0 || !strings.EqualFold(set.GetHintedBranchName(strings.Fields(record.Source)[0], set.NoHint), name) {
+			logger.Print(" => Profile of address %#04X is %s and found %s, no hint set", addresses[i], record.Source, line)
+			continue
+		}
+		hint := set.UnlikelyHint
+		if record.TakenRate() >= 0.5 {
+			hint = set.LikelyHint
+		}
+		hintedName := set.GetHintedBranchName(name, hint)
+		if name == strings.ToUpper(name) {
+			hintedName = strings.ToUpper(hintedName)
+		}
+		lines[i] = hintedName + strings.TrimSpace(line)[len(mnemonic):]
+		hinted[hint] += 1
+	}
+	logger.Print(" => Branch hints set from profile: %d likely, %d unlikely", hinted[set.LikelyHint], hinted[set.UnlikelyHint])
+	return lines
+}
+
+func getInstructionSizes(instructionSet set.Set, lines []string, labelLines map[string]uint32, alignment uint32, compressed bool, base uint32) ([]uint32, error) {
+	sizes := make([]uint32, len(lines))
+	for i := range sizes {
+		sizes[i] = consts.BYTES_PER_WORD
+		if compressed {
+			sizes[i] = consts.BYTES_PER_HALFWORD
+		}
+	}
+	if !compressed {
+		return sizes, nil
+	}
+
+	// Start with every instruction compressed and expand the ones that do not fit until addresses are stable
+	for changed := true; changed; {
+		changed = false
+		addresses, labels := getAddressesAndLabels(sizes, labelLines, base)
+		for i, line := range lines {
+			if sizes[i] != consts.BYTES_PER_HALFWORD {
+				continue
+			}
+			instruction, err := instructionSet.GetInstructionFromString(line, addresses[i]+sizes[i], alignment, labels)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Failed translating line %d: %s. %s", i, line, err.Error()))
+			}
+			if set.GetBranchHint(instruction.Info.Opcode) != set.NoHint {
+				return nil, errors.New(fmt.Sprintf("Failed translating line %d: %s. Branch hints are not available with compressed instructions", i, line))
+			}
+			if _, ok := set.Compress(instruction, addresses[i]); !ok {
+				sizes[i] = consts.BYTES_PER_WORD
+				changed = true
+			}
+		}
+	}
+	return sizes, nil
+}
+
+func getAddressesAndLabels(sizes []uint32, labelLines map[string]uint32, base uint32) ([]uint32, map[string]uint32) {
+	addresses := make([]uint32, len(sizes)+1)
+	addresses[0] = base
+	for i, size := range sizes {
+		addresses[i+1] = addresses[i] + size
+	}
+	labels := map[string]uint32{}
+	for label, line := range labelLines {
+		labels[label] = addresses[line]
+	}
+	return addresses, labels
+}
+
 func getLinesAndMapLabels(lines []string) ([]string, []string, map[string]uint32) {
 	memory := []string{}
 	cleanLines := []string{}
 	labels := map[string]uint32{}
-	address := uint32(0)
 	for _, line := range lines {
 		// Remove comments in the right
 		line = strings.Split(line, ";")[0]
@@ -72,10 +196,9 @@ func getLinesAndMapLabels(lines []string) ([]string, []string, map[string]uint32
 		}
 		// Assert if line is a label
 		if line[len(line)-1:] == ":" {
-			labels[line[:len(line)-1]] = address
+			labels[line[:len(line)-1]] = uint32(len(cleanLines))
 		} else {
 			cleanLines = append(cleanLines, line)
-			address += consts.BYTES_PER_WORD
 		}
 	}
 	return memory, cleanLines, labels